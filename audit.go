@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// canonicalHistory is the history in thread order, by thread and then by
+// invocation, with any remaining ties broken on the record itself so the
+// order does not depend on how the methods were collected
+func canonicalHistory(methods []Method) []methodRecord {
+	records := make([]methodRecord, len(methods))
+	for i := range methods {
+		records[i] = toRecord(&methods[i])
+	}
+	sort.Slice(records, func(a, b int) bool {
+		ra, rb := &records[a], &records[b]
+		switch {
+		case ra.Thread != rb.Thread:
+			return ra.Thread < rb.Thread
+		case ra.Invocation != rb.Invocation:
+			return ra.Invocation < rb.Invocation
+		case ra.Response != rb.Response:
+			return ra.Response < rb.Response
+		}
+		ja, _ := json.Marshal(ra)
+		jb, _ := json.Marshal(rb)
+		return bytes.Compare(ja, jb) < 0
+	})
+	return records
+}
+
+// merkleRoot is the RFC 6962 Merkle tree hash of the leaves: a leaf is
+// hashed behind a 0 byte, a node behind a 1, and the left subtree of n
+// leaves holds the largest power of two below n
+func merkleRoot(leaves [][]byte) []byte {
+	switch len(leaves) {
+	case 0:
+		h := sha256.Sum256(nil)
+		return h[:]
+	case 1:
+		h := sha256.Sum256(append([]byte{0}, leaves[0]...))
+		return h[:]
+	}
+	k := 1
+	for k*2 < len(leaves) {
+		k *= 2
+	}
+	node := append([]byte{1}, merkleRoot(leaves[:k])...)
+	node = append(node, merkleRoot(leaves[k:])...)
+	h := sha256.Sum256(node)
+	return h[:]
+}
+
+// historyRoot is the Merkle root over the JSON records of the canonical history
+func historyRoot(methods []Method) string {
+	records := canonicalHistory(methods)
+	leaves := make([][]byte, len(records))
+	for i := range records {
+		leaves[i], _ = json.Marshal(records[i])
+	}
+	return hex.EncodeToString(merkleRoot(leaves))
+}
+
+// verdictReport binds a verdict to the history it was reached on
+type verdictReport struct {
+	Condition  string         `json:"condition"`
+	Relax      string         `json:"relax,omitempty"` // allowed rank errors, strict when empty
+	Methods    int            `json:"methods"`
+	Root       string         `json:"root"`
+	Correct    bool           `json:"correct"`
+	Violations []string       `json:"violations"`
+	RankErrors []int          `json:"rank_errors,omitempty"`
+	Genesis    map[string]int `json:"genesis,omitempty"` // a ledger's starting balances
+}
+
+func newReport(methods []Method, genesis map[string]int) verdictReport {
+	r := verdictReport{
+		Condition:  condition.String(),
+		Relax:      relaxationString(relaxation),
+		Methods:    len(methods),
+		Root:       historyRoot(methods),
+		Correct:    finalOutcome,
+		Violations: finalViolations,
+		RankErrors: rankErrors,
+		Genesis:    genesis,
+	}
+	if r.Violations == nil {
+		r.Violations = []string{}
+	}
+	return r
+}
+
+// writeHistory writes the canonical history as JSON lines
+func writeHistory(path string, methods []Method) error {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	for _, r := range canonicalHistory(methods) {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, b.Bytes(), 0o644)
+}
+
+func writeReport(path string, r verdictReport) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+// sameViolations compares two lists of violating items as sets
+func sameViolations(a, b []string) bool {
+	set := make(map[string]bool)
+	for _, k := range a {
+		set[k] = true
+	}
+	other := make(map[string]bool)
+	for _, k := range b {
+		if !set[k] {
+			return false
+		}
+		other[k] = true
+	}
+	return len(set) == len(other)
+}
+
+// auditReport checks that a report belongs to exactly the history: the
+// roots match, and verifying the history again reaches the same verdict
+func auditReport(r verdictReport, methods []Method) []string {
+	var problems []string
+
+	if root := historyRoot(methods); root != r.Root {
+		problems = append(problems, fmt.Sprintf("history root %s, report has %s", root, r.Root))
+	}
+	if len(methods) != r.Methods {
+		problems = append(problems, fmt.Sprintf("history has %d methods, report %d", len(methods), r.Methods))
+	}
+
+	c, err := parseCondition(r.Condition)
+	if err != nil {
+		return append(problems, err.Error())
+	}
+	k, err := parseRelaxation(r.Relax)
+	if err != nil {
+		return append(problems, err.Error())
+	}
+	saved, savedK := condition, relaxation
+	condition, relaxation = c, k
+	defer func() { condition, relaxation = saved, savedK }()
+
+	verdict := verifyHistory(methods)
+	finalOutcome, finalViolations = verdict.correct, verdict.violations
+	if r.Genesis != nil {
+		checkLedger(methods, r.Genesis)
+	}
+	if finalOutcome != r.Correct || !sameViolations(finalViolations, r.Violations) {
+		problems = append(problems, fmt.Sprintf("history verifies as correct = %v with violations %v, report has correct = %v with %v",
+			finalOutcome, finalViolations, r.Correct, r.Violations))
+	}
+	return problems
+}
+
+// audit confirms a report against a history:
+// verifier audit -report report.json history.ndjson
+func audit(args []string) {
+	fs := flag.NewFlagSet("audit", flag.ExitOnError)
+	reportPath := fs.String("report", "report.json", "verdict report to check")
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		fmt.Println("usage: verifier audit -report report.json history.ndjson")
+		os.Exit(2)
+	}
+
+	b, err := os.ReadFile(*reportPath)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	var r verdictReport
+	if err := json.Unmarshal(b, &r); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	methods, err := readRecords(f)
+	f.Close()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	problems := auditReport(r, methods)
+	for _, p := range problems {
+		fmt.Printf("WARNING: %s\n", p)
+	}
+	if len(problems) != 0 {
+		fmt.Println("-------------Report Does Not Match History-------------")
+		os.Exit(1)
+	}
+	fmt.Printf("Report matches history, root %s\n", r.Root)
+}
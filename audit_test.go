@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMerkleRoot(t *testing.T) {
+	// RFC 6962 test vectors from Certificate Transparency
+	leaves := [][]byte{
+		{}, {0x00}, {0x10}, {0x20, 0x21}, {0x30, 0x31}, {0x40, 0x41, 0x42, 0x43},
+		{0x50, 0x51, 0x52, 0x53, 0x54, 0x55, 0x56, 0x57},
+		{0x60, 0x61, 0x62, 0x63, 0x64, 0x65, 0x66, 0x67, 0x68, 0x69, 0x6a, 0x6b, 0x6c, 0x6d, 0x6e, 0x6f},
+	}
+	tests := []struct {
+		n    int
+		root string
+	}{
+		{0, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{1, "6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d"},
+		{2, "fac54203e7cc696cf0dfcb42c92a1d9dbaf70ad9e621f4bd8d98662f00e3c125"},
+		{3, "aeb6bcfe274b70a14fb067a5e5578264db0fa9b51af5e0ba159158f329e06e77"},
+		{8, "5dc9da79a70659a9ad559cb701ded9a2ab9d823aad2f4960cfe370eff4604328"},
+	}
+	for _, tt := range tests {
+		if got := hex.EncodeToString(merkleRoot(leaves[:tt.n])); got != tt.root {
+			t.Errorf("root of %d leaves %s, want %s", tt.n, got, tt.root)
+		}
+	}
+}
+
+func TestHistoryRoot(t *testing.T) {
+	methods := []Method{
+		op(PRODUCER, FIFO, "a", true, 0, 1),
+		op(PRODUCER, FIFO, "b", true, 2, 3),
+		op(CONSUMER, FIFO, "a", true, 4, 5),
+	}
+	methods[1].process = 1
+	root := historyRoot(methods)
+
+	// thread order, not the order the methods were collected in
+	shuffled := []Method{methods[2], methods[1], methods[0]}
+	if got := historyRoot(shuffled); got != root {
+		t.Errorf("root changes with collection order: %s vs %s", got, root)
+	}
+
+	methods[2].status = false
+	if historyRoot(methods) == root {
+		t.Error("root does not change with the history")
+	}
+}
+
+func TestAuditReport(t *testing.T) {
+	methods := []Method{
+		op(PRODUCER, FIFO, "a", true, 0, 1),
+		op(PRODUCER, FIFO, "b", true, 2, 3),
+		op(CONSUMER, FIFO, "b", true, 4, 5),
+		op(CONSUMER, FIFO, "a", true, 6, 7),
+	}
+	v := verifyHistory(methods)
+	finalOutcome, finalViolations = v.correct, v.violations
+	report := newReport(methods, nil)
+	if report.Correct || len(report.Violations) != 1 {
+		t.Fatalf("report %+v", report)
+	}
+
+	// through the files audit reads
+	dir := t.TempDir()
+	path := filepath.Join(dir, "history.ndjson")
+	if err := writeHistory(path, methods); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	read, err := readRecords(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problems := auditReport(report, read); len(problems) != 0 {
+		t.Errorf("audit of the report's own history: %q", problems)
+	}
+
+	tampered := append([]Method(nil), read...)
+	tampered[3].itemAddrS = "c"
+	if problems := auditReport(report, tampered); len(problems) == 0 {
+		t.Error("audit accepted a different history")
+	}
+
+	forged := report
+	forged.Correct, forged.Violations = true, []string{}
+	if problems := auditReport(forged, read); len(problems) != 1 {
+		t.Errorf("audit of a forged verdict: %q", problems)
+	}
+}
+
+func TestAuditLedgerReport(t *testing.T) {
+	methods := transfers(
+		transfer("a", "b", 3, 10, 0, 0, 1),
+		transfer("b", "a", 1, 3, 7, 2, 3),
+	)
+	genesis := map[string]int{"a": 10}
+	v := verifyHistory(methods)
+	finalOutcome, finalViolations = v.correct, v.violations
+	checkLedger(methods, genesis)
+	report := newReport(methods, genesis)
+	if !report.Correct {
+		t.Fatalf("report %+v", report)
+	}
+	if problems := auditReport(report, methods); len(problems) != 0 {
+		t.Errorf("audit: %q", problems)
+	}
+
+	// consumers ahead of their producers, as in the canonical history
+	reversed := make([]Method, len(methods))
+	for i := range methods {
+		reversed[len(methods)-1-i] = methods[i]
+	}
+	if problems := auditReport(report, reversed); len(problems) != 0 {
+		t.Errorf("audit of the reversed history: %q", problems)
+	}
+
+	// the same history from other balances does not add up
+	report.Genesis = map[string]int{"a": 11}
+	if problems := auditReport(report, methods); len(problems) != 1 {
+		t.Errorf("audit with other genesis balances: %q", problems)
+	}
+}
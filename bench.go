@@ -0,0 +1,369 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"os/exec"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// benchConfig is one point of a benchmark sweep
+type benchConfig struct {
+	threads   int
+	semantics Semantics
+	producers int // percent of operations that produce
+	ops       int // operations across all threads
+	seed      int64
+}
+
+type benchItem struct {
+	key   string
+	value int
+}
+
+// benchQueue is the structure the benchmark workload runs against, a
+// lock-based one so its histories are correct
+type benchQueue struct {
+	sync.Mutex
+	semantics Semantics
+	items     []benchItem
+}
+
+func (q *benchQueue) produce(it benchItem) {
+	q.Lock()
+	q.items = append(q.items, it)
+	q.Unlock()
+}
+
+func (q *benchQueue) consume() (benchItem, bool) {
+	q.Lock()
+	defer q.Unlock()
+	if len(q.items) == 0 {
+		return benchItem{}, false
+	}
+	i := len(q.items) - 1 // LIFO, and any item for SET
+	switch q.semantics {
+	case FIFO:
+		i = 0
+	case PRIORITY:
+		i = 0
+		for j := range q.items {
+			if q.items[j].value < q.items[i].value {
+				i = j
+			}
+		}
+	}
+	it := q.items[i]
+	q.items = append(q.items[:i], q.items[i+1:]...)
+	return it, true
+}
+
+// runWorkload runs the operations of c on threads goroutines, and returns
+// the history when asked to record it
+func runWorkload(c benchConfig, record bool) ([]Method, time.Duration) {
+	q := &benchQueue{semantics: c.semantics}
+	lists := make([][]Method, c.threads)
+	var wg sync.WaitGroup
+	begin := time.Now()
+	for id := 0; id < c.threads; id++ {
+		n := c.ops / c.threads
+		if id < c.ops%c.threads {
+			n++
+		}
+		if record {
+			lists[id] = make([]Method, 0, n)
+		}
+		wg.Add(1)
+		go func(id, n int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(c.seed + int64(id)))
+			for i := 0; i < n; i++ {
+				produce := rng.Intn(100) < c.producers
+				it := benchItem{key: strconv.Itoa(id) + "-" + strconv.Itoa(i), value: rng.Intn(1 << 30)}
+				ok := true
+
+				var invocation int64
+				if record {
+					invocation = time.Since(begin).Nanoseconds()
+				}
+				if produce {
+					q.produce(it)
+				} else {
+					it, ok = q.consume()
+				}
+				if !record {
+					continue
+				}
+				response := time.Since(begin).Nanoseconds()
+
+				var m Method
+				if produce {
+					m.setMethod(i, it.key, "", it.value, c.semantics, PRODUCER, true, id, 0, 0)
+				} else {
+					m.setMethod(i, it.key, "", it.value, c.semantics, CONSUMER, ok, id, 0, 0)
+				}
+				m.invocation = invocation
+				m.response = response
+				m.process = id
+				lists[id] = append(lists[id], m)
+			}
+		}(id, n)
+	}
+	wg.Wait()
+	elapsed := time.Since(begin)
+
+	var methods []Method
+	for _, l := range lists {
+		methods = append(methods, l...)
+	}
+	return methods, elapsed
+}
+
+// benchMachine is where and on what a benchmark ran
+type benchMachine struct {
+	Revision  string `json:"revision"`
+	GoVersion string `json:"go_version"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+	CPUs      int    `json:"cpus"`
+	CPU       string `json:"cpu,omitempty"`
+	Host      string `json:"host"`
+}
+
+type benchResult struct {
+	benchMachine
+	Time      string `json:"time"`
+	Threads   int    `json:"threads"`
+	Semantics string `json:"semantics"`
+	Producers int    `json:"producers"`
+	Ops       int    `json:"ops"`
+
+	Throughput         float64 `json:"throughput"`          // operations per second without verification
+	VerifiedThroughput float64 `json:"verified_throughput"` // operations per second recorded and verified
+	VerifierThroughput float64 `json:"verifier_throughput"` // methods verified per second
+	AllocBytes         uint64  `json:"alloc_bytes"`         // allocated recording and verifying
+	Correct            bool    `json:"correct"`
+}
+
+var benchColumns = []string{"time", "revision", "go_version", "os", "arch", "cpus", "cpu", "host",
+	"threads", "semantics", "producers", "ops", "throughput", "verified_throughput", "verifier_throughput",
+	"alloc_bytes", "correct"}
+
+func (r benchResult) row() []string {
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', 1, 64) }
+	return []string{r.Time, r.Revision, r.GoVersion, r.OS, r.Arch, strconv.Itoa(r.CPUs), r.CPU, r.Host,
+		strconv.Itoa(r.Threads), r.Semantics, strconv.Itoa(r.Producers), strconv.Itoa(r.Ops),
+		f(r.Throughput), f(r.VerifiedThroughput), f(r.VerifierThroughput),
+		strconv.FormatUint(r.AllocBytes, 10), strconv.FormatBool(r.Correct)}
+}
+
+// revision is the git revision the binary was built from, or the one of the
+// working directory under go run and go test
+func revision() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		rev, dirty := "", false
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				rev = s.Value
+			case "vcs.modified":
+				dirty = s.Value == "true"
+			}
+		}
+		if rev != "" {
+			if dirty {
+				rev += "-dirty"
+			}
+			return rev
+		}
+	}
+	if out, err := exec.Command("git", "rev-parse", "HEAD").Output(); err == nil {
+		return strings.TrimSpace(string(out))
+	}
+	return "unknown"
+}
+
+// cpuModel is the first model name in /proc/cpuinfo, where there is one
+func cpuModel() string {
+	f, err := os.Open("/proc/cpuinfo")
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if name, value, ok := strings.Cut(sc.Text(), ":"); ok && strings.TrimSpace(name) == "model name" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+func machine() benchMachine {
+	host, _ := os.Hostname()
+	return benchMachine{
+		Revision:  revision(),
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		CPUs:      runtime.NumCPU(),
+		CPU:       cpuModel(),
+		Host:      host,
+	}
+}
+
+// measure runs the workload of c once bare and once recorded and verified
+func measure(c benchConfig) benchResult {
+	r := benchResult{
+		Time:      time.Now().UTC().Format(time.RFC3339),
+		Threads:   c.threads,
+		Semantics: c.semantics.String(),
+		Producers: c.producers,
+		Ops:       c.ops,
+	}
+
+	_, bare := runWorkload(c, false)
+	r.Throughput = float64(c.ops) / bare.Seconds()
+
+	runtime.GC()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	methods, recorded := runWorkload(c, true)
+	began := time.Now()
+	v := verifyHistory(methods)
+	verifying := time.Since(began)
+	runtime.ReadMemStats(&after)
+
+	r.VerifiedThroughput = float64(c.ops) / (recorded + verifying).Seconds()
+	r.VerifierThroughput = float64(len(methods)) / verifying.Seconds()
+	r.AllocBytes = after.TotalAlloc - before.TotalAlloc
+	r.Correct = v.correct
+	return r
+}
+
+// writeBench writes results as CSV rows or JSON lines, with a CSV header
+// only when header is set
+func writeBench(w io.Writer, format string, results []benchResult, header bool) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		for _, r := range results {
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "csv":
+		cw := csv.NewWriter(w)
+		if header {
+			if err := cw.Write(benchColumns); err != nil {
+				return err
+			}
+		}
+		for _, r := range results {
+			if err := cw.Write(r.row()); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	return fmt.Errorf("unknown format %q, want csv or json", format)
+}
+
+func parseInts(s string) ([]int, error) {
+	var ns []int
+	for _, f := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil {
+			return nil, err
+		}
+		ns = append(ns, n)
+	}
+	return ns, nil
+}
+
+// bench sweeps thread counts, operation mixes and semantics:
+// verifier bench [-threads 1,2,4,8] [-producers 50] [-semantics fifo,lifo,set,priority] [-ops 2000] [-format csv] [-o results.csv]
+func bench(args []string) {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	threadsFlag := fs.String("threads", "1,2,4,8", "thread counts to sweep")
+	producersFlag := fs.String("producers", "50", "percentages of producing operations to sweep")
+	semanticsFlag := fs.String("semantics", "fifo,lifo,set,priority", "semantics to sweep")
+	ops := fs.Int("ops", 2000, "operations per run")
+	seed := fs.Int64("seed", 1, "seed of the operation mix")
+	format := fs.String("format", "csv", "csv or json (one object per line)")
+	out := fs.String("o", "", "append results to this file instead of writing them to stdout")
+	_ = fs.Parse(args)
+
+	threads, err := parseInts(*threadsFlag)
+	if err == nil {
+		for _, n := range threads {
+			if n < 1 {
+				err = fmt.Errorf("thread count %d", n)
+			}
+		}
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	producers, err := parseInts(*producersFlag)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	var semantics []Semantics
+	for _, name := range strings.Split(*semanticsFlag, ",") {
+		s, err := parseSemantics(strings.TrimSpace(name))
+		if err == nil && s == MAPP {
+			err = fmt.Errorf("no benchmark workload for map semantics")
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+		semantics = append(semantics, s)
+	}
+
+	m := machine()
+	var results []benchResult
+	for _, s := range semantics {
+		for _, p := range producers {
+			for _, n := range threads {
+				r := measure(benchConfig{threads: n, semantics: s, producers: p, ops: *ops, seed: *seed})
+				r.benchMachine = m
+				results = append(results, r)
+			}
+		}
+	}
+
+	w, header := io.Writer(os.Stdout), true
+	if *out != "" {
+		f, err := os.OpenFile(*out, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		if info, err := f.Stat(); err == nil && info.Size() != 0 {
+			header = false
+		}
+		w = f
+	}
+	if err := writeBench(w, *format, results, header); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
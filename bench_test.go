@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestBenchWorkload(t *testing.T) {
+	for _, s := range []Semantics{FIFO, LIFO, SET, PRIORITY} {
+		t.Run(s.String(), func(t *testing.T) {
+			c := benchConfig{threads: 4, semantics: s, producers: 60, ops: 400, seed: 1}
+			methods, _ := runWorkload(c, true)
+			if len(methods) != c.ops {
+				t.Fatalf("recorded %d methods of %d operations", len(methods), c.ops)
+			}
+			if v := verifyHistory(methods); !v.correct {
+				t.Errorf("lock-based %v workload violates %v", s, v.violations)
+			}
+		})
+	}
+}
+
+func TestWriteBench(t *testing.T) {
+	r := measure(benchConfig{threads: 2, semantics: FIFO, producers: 50, ops: 200, seed: 1})
+	r.benchMachine = machine()
+	if !r.Correct || r.Throughput <= 0 || r.VerifiedThroughput <= 0 || r.VerifierThroughput <= 0 || r.AllocBytes == 0 {
+		t.Fatalf("result %+v", r)
+	}
+
+	var b bytes.Buffer
+	if err := writeBench(&b, "csv", []benchResult{r, r}, true); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&b).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || len(rows[1]) != len(benchColumns) || rows[1][9] != "fifo" {
+		t.Errorf("csv %q", rows)
+	}
+
+	b.Reset()
+	if err := writeBench(&b, "json", []benchResult{r}, true); err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["revision"] != r.Revision || got["threads"] != float64(2) || got["correct"] != true {
+		t.Errorf("json %v", got)
+	}
+
+	if err := writeBench(&b, "xml", nil, true); err == nil {
+		t.Error("unknown format accepted")
+	}
+}
+
+// sweep runs f for every thread count and semantics of the benchmarks
+func sweep(b *testing.B, f func(b *testing.B, c benchConfig)) {
+	for _, s := range []Semantics{FIFO, LIFO, SET, PRIORITY} {
+		for _, n := range []int{1, 2, 4, 8} {
+			c := benchConfig{threads: n, semantics: s, producers: 50, seed: 1}
+			b.Run(fmt.Sprintf("%v/threads=%d", s, n), func(b *testing.B) { f(b, c) })
+		}
+	}
+}
+
+func BenchmarkWorkload(b *testing.B) {
+	sweep(b, func(b *testing.B, c benchConfig) {
+		c.ops = b.N
+		_, elapsed := runWorkload(c, false)
+		b.ReportMetric(float64(b.N)/elapsed.Seconds(), "ops/s")
+	})
+}
+
+func BenchmarkWorkloadRecorded(b *testing.B) {
+	sweep(b, func(b *testing.B, c benchConfig) {
+		c.ops = b.N
+		b.ReportAllocs()
+		_, elapsed := runWorkload(c, true)
+		b.ReportMetric(float64(b.N)/elapsed.Seconds(), "ops/s")
+	})
+}
+
+// BenchmarkVerify verifies a history of 1000 methods per iteration
+func BenchmarkVerify(b *testing.B) {
+	sweep(b, func(b *testing.B, c benchConfig) {
+		c.ops = 1000
+		methods, _ := runWorkload(c, true)
+		history := make([]Method, len(methods))
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			copy(history, methods)
+			verifyHistory(history)
+		}
+		b.ReportMetric(float64(len(methods)*b.N)/b.Elapsed().Seconds(), "methods/s")
+	})
+}
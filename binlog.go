@@ -0,0 +1,422 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// A binary log holds the methods one thread recorded. It starts with a
+// header and every record after it is a uvarint length, the payload and a
+// CRC-32C of the payload:
+//
+//	header:  "VLOG" version condition semantics uvarint(thread) varint(start) crc32c
+//	payload: type semantics status varint(invocation) varint(response)
+//	         string(key) string(receiver) varint(value) varint(amount)
+//	         varint(nonce) varint(txnCtr) varint(id)
+//
+// strings are a uvarint length and the bytes. semantics in the header is the
+// workload's, every record carries its own.
+const (
+	binlogMagic   = "VLOG"
+	binlogVersion = 1
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// no method encodes to anywhere near this, a longer record is damage
+const maxBinlogRecord = 1 << 20
+
+// binlogHeader describes the history in a log
+type binlogHeader struct {
+	condition Condition
+	semantics Semantics
+	thread    int
+	start     int64 // unix nanoseconds the run started at
+}
+
+func binlogPath(dir string, thread int) string {
+	return filepath.Join(dir, fmt.Sprintf("thread-%02d.vlog", thread))
+}
+
+type binlogWriter struct {
+	f   *os.File
+	buf []byte
+}
+
+// createBinlog starts the log of a thread, replacing any earlier one
+func createBinlog(path string, h binlogHeader) (*binlogWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	b := []byte(binlogMagic)
+	b = append(b, binlogVersion, byte(h.condition), byte(h.semantics))
+	b = binary.AppendUvarint(b, uint64(h.thread))
+	b = binary.AppendVarint(b, h.start)
+	b = binary.LittleEndian.AppendUint32(b, crc32.Checksum(b, castagnoli))
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &binlogWriter{f: f}, nil
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+func encodeMethod(b []byte, m *Method) []byte {
+	status := byte(0)
+	if m.status {
+		status = 1
+	}
+	b = append(b, byte(m.types), byte(m.semantics), status)
+	b = binary.AppendVarint(b, m.invocation)
+	b = binary.AppendVarint(b, m.response)
+	b = appendString(b, m.itemAddrS)
+	b = appendString(b, m.itemAddrR)
+	b = binary.AppendVarint(b, int64(m.itemBalance))
+	b = binary.AppendVarint(b, int64(m.requestAmnt))
+	b = binary.AppendVarint(b, m.nonce)
+	b = binary.AppendVarint(b, int64(m.txnCtr))
+	b = binary.AppendVarint(b, int64(m.id))
+	return b
+}
+
+// append writes one record in a single write, so a crash leaves at most
+// that record torn
+func (w *binlogWriter) append(m *Method) error {
+	payload := encodeMethod(w.buf[:0], m)
+	rec := binary.AppendUvarint(make([]byte, 0, len(payload)+binary.MaxVarintLen64+4), uint64(len(payload)))
+	rec = append(rec, payload...)
+	rec = binary.LittleEndian.AppendUint32(rec, crc32.Checksum(payload, castagnoli))
+	w.buf = payload
+	_, err := w.f.Write(rec)
+	return err
+}
+
+func (w *binlogWriter) close() error {
+	return w.f.Close()
+}
+
+var errBinlogRecord = errors.New("malformed record")
+
+// decoder reads the fields of a payload in order
+type decoder struct {
+	b   []byte
+	err error
+}
+
+func (d *decoder) byte() byte {
+	if d.err != nil || len(d.b) == 0 {
+		d.err = errBinlogRecord
+		return 0
+	}
+	c := d.b[0]
+	d.b = d.b[1:]
+	return c
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.b)
+	if n <= 0 {
+		d.err = errBinlogRecord
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.b)
+	if n <= 0 {
+		d.err = errBinlogRecord
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) string() string {
+	n := d.uvarint()
+	if d.err != nil || n > uint64(len(d.b)) {
+		d.err = errBinlogRecord
+		return ""
+	}
+	s := string(d.b[:n])
+	d.b = d.b[n:]
+	return s
+}
+
+func decodeMethod(payload []byte, thread int) (Method, error) {
+	d := decoder{b: payload}
+	var m Method
+	m.types = Types(d.byte())
+	m.semantics = Semantics(d.byte())
+	m.status = d.byte() == 1
+	m.invocation = d.varint()
+	m.response = d.varint()
+	m.itemAddrS = d.string()
+	m.itemAddrR = d.string()
+	m.itemBalance = int(d.varint())
+	m.requestAmnt = int(d.varint())
+	m.nonce = d.varint()
+	m.txnCtr = int32(d.varint())
+	m.id = int(d.varint())
+	if d.err == nil && (len(d.b) != 0 || int(m.types) >= len(typesNames) || int(m.semantics) >= len(semanticsNames)) {
+		d.err = errBinlogRecord
+	}
+	m.senderID = m.id
+	m.process = thread
+	return m, d.err
+}
+
+// readBinlog reads a thread's log. A record cut short or failing its
+// checksum at the end of the log is what a crash mid-write leaves: it is
+// dropped and its size returned as torn. Damage before the last record is
+// an error.
+func readBinlog(r io.Reader) (h binlogHeader, methods []Method, torn int64, err error) {
+	br := bufio.NewReader(r)
+
+	head := make([]byte, len(binlogMagic)+3)
+	if _, err := io.ReadFull(br, head); err != nil {
+		return h, nil, 0, fmt.Errorf("header: %w", err)
+	}
+	if string(head[:len(binlogMagic)]) != binlogMagic {
+		return h, nil, 0, fmt.Errorf("not a verifier log")
+	}
+	if v := head[len(binlogMagic)]; v != binlogVersion {
+		return h, nil, 0, fmt.Errorf("log version %d, want %d", v, binlogVersion)
+	}
+	h.condition = Condition(head[len(binlogMagic)+1])
+	h.semantics = Semantics(head[len(binlogMagic)+2])
+	crc := crc32.Update(0, castagnoli, head)
+	thread, err := binary.ReadUvarint(&crcReader{br, &crc})
+	if err != nil {
+		return h, nil, 0, fmt.Errorf("header: %w", err)
+	}
+	h.thread = int(thread)
+	if h.start, err = binary.ReadVarint(&crcReader{br, &crc}); err != nil {
+		return h, nil, 0, fmt.Errorf("header: %w", err)
+	}
+	var sum [4]byte
+	if _, err := io.ReadFull(br, sum[:]); err != nil {
+		return h, nil, 0, fmt.Errorf("header: %w", err)
+	}
+	if binary.LittleEndian.Uint32(sum[:]) != crc {
+		return h, nil, 0, fmt.Errorf("header checksum mismatch")
+	}
+
+	for n := 0; ; n++ {
+		length, err := binary.ReadUvarint(&countingReader{br, &torn})
+		switch {
+		case err == io.EOF:
+			return h, methods, 0, nil
+		case err == io.ErrUnexpectedEOF:
+			return h, methods, torn, nil
+		case err != nil:
+			return h, methods, 0, fmt.Errorf("record %d: %w", n, err)
+		case length > maxBinlogRecord:
+			return h, methods, 0, fmt.Errorf("record %d: length %d", n, length)
+		}
+		rec := make([]byte, length+4)
+		read, err := io.ReadFull(br, rec)
+		torn += int64(read)
+		if err != nil {
+			return h, methods, torn, nil
+		}
+		payload := rec[:length]
+		if binary.LittleEndian.Uint32(rec[length:]) != crc32.Checksum(payload, castagnoli) {
+			if rest := drain(br); rest != 0 {
+				return h, methods, 0, fmt.Errorf("record %d: checksum mismatch with %d bytes after it", n, rest)
+			}
+			return h, methods, torn, nil
+		}
+		m, err := decodeMethod(payload, h.thread)
+		if err != nil {
+			return h, methods, 0, fmt.Errorf("record %d: %w", n, err)
+		}
+		methods = append(methods, m)
+		torn = 0
+	}
+}
+
+// crcReader folds the bytes read into a running CRC-32C
+type crcReader struct {
+	r   *bufio.Reader
+	crc *uint32
+}
+
+func (c *crcReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		*c.crc = crc32.Update(*c.crc, castagnoli, []byte{b})
+	}
+	return b, err
+}
+
+// countingReader counts the bytes read into n
+type countingReader struct {
+	r *bufio.Reader
+	n *int64
+}
+
+func (c *countingReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		*c.n++
+	}
+	return b, err
+}
+
+// drain reads the rest of r and returns how many bytes there were
+func drain(r io.Reader) int64 {
+	n, _ := io.Copy(io.Discard, r)
+	return n
+}
+
+// readBinlogDir reads the logs of every thread in dir and returns the
+// header of the first. All of them have to be from one run under one
+// condition.
+func readBinlogDir(dir string) (binlogHeader, []Method, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.vlog"))
+	if err != nil {
+		return binlogHeader{}, nil, err
+	}
+	if len(paths) == 0 {
+		return binlogHeader{}, nil, fmt.Errorf("no .vlog files in %s", dir)
+	}
+	sort.Strings(paths)
+
+	var methods []Method
+	var first *binlogHeader
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return binlogHeader{}, nil, err
+		}
+		h, ms, torn, err := readBinlog(f)
+		f.Close()
+		if err != nil {
+			return binlogHeader{}, nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if torn != 0 {
+			logAt(collectorLog, slog.LevelWarn, "dropped a torn record at the end", "path", path, "bytes", torn)
+		}
+		if first == nil {
+			first = &h
+		} else if h.condition != first.condition || h.start != first.start {
+			return binlogHeader{}, nil, fmt.Errorf("%s is from another run than %s", path, paths[0])
+		}
+		methods = append(methods, ms...)
+	}
+	return *first, methods, nil
+}
+
+// check re-verifies the binary logs of a run:
+// verifier check [-condition c] [-relax fifo=k] [-state file [-state-every d]] [-timeout d] <dir>
+// It exits 1 when the history is incorrect and 3 when it stopped before
+// the end without finding it so.
+func check(args []string) {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	conditionFlag := fs.String("condition", "", "correctness condition, the one in the logs by default")
+	relaxFlag := fs.String("relax", "", "allowed rank error per semantics, e.g. fifo=2,priority=1")
+	stateFlag := fs.String("state", "", "save the verifier state to this file at checkpoints, and resume from it")
+	everyFlag := fs.Duration("state-every", 30*time.Second, "least time between two saves of the state")
+	timeoutFlag := fs.Duration("timeout", 0, "stop verifying after this long")
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		fmt.Println("usage: verifier check [-condition c] [-relax fifo=k] [-state file [-state-every d]] [-timeout d] <dir>")
+		os.Exit(2)
+	}
+
+	h, methods, err := readBinlogDir(fs.Arg(0))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	condition = h.condition
+	if *conditionFlag != "" {
+		if condition, err = parseCondition(*conditionFlag); err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+	}
+	if relaxation, err = parseRelaxation(*relaxFlag); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+
+	fmt.Printf("Checking %d methods under %v\n", len(methods), condition)
+	ctx, cancel := verifyContext(*timeoutFlag)
+	defer cancel()
+	v, err := verifyLogs(ctx, h, methods, *stateFlag, *everyFlag)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if !reportVerdict(v) {
+		os.Exit(1)
+	}
+	if v.stopped != nil {
+		os.Exit(3)
+	}
+}
+
+// binlogs are the workers' logs when main runs with -binlog
+var binlogs []*binlogWriter
+
+// openBinlogs starts a log per worker in dir
+func openBinlogs(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	binlogs = make([]*binlogWriter, numThreads)
+	for i := range binlogs {
+		w, err := createBinlog(binlogPath(dir, i), binlogHeader{condition, FIFO, i, start.UnixNano()})
+		if err != nil {
+			closeBinlogs()
+			return err
+		}
+		binlogs[i] = w
+	}
+	return nil
+}
+
+func closeBinlogs() {
+	for _, w := range binlogs {
+		if w != nil {
+			w.close()
+		}
+	}
+	binlogs = nil
+}
+
+// logMethods appends methods a worker recorded to its log, if it has one
+func logMethods(id int, ms ...*Method) {
+	if binlogs == nil {
+		return
+	}
+	for _, m := range ms {
+		if err := binlogs[id].append(m); err != nil {
+			logAt(workerLog, slog.LevelError, "binary log", "thread", id, "err", err)
+		}
+	}
+}
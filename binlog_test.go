@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeBinlog logs methods as thread of a run started at start
+func writeBinlog(t *testing.T, path string, thread int, start int64, methods []Method) {
+	t.Helper()
+	w, err := createBinlog(path, binlogHeader{LINEARIZABILITY, FIFO, thread, start})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range methods {
+		if err := w.append(&methods[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBinlogRoundTrip(t *testing.T) {
+	methods := []Method{
+		opValue(PRODUCER, FIFO, "a", 7, true, 0, 10),
+		opValue(CONSUMER, FIFO, "a", 7, true, 12, 20),
+		opValue(WRITER, MAPP, "a/memo", -3, true, 21, 22),
+		op(CONSUMER, LIFO, "", false, 23, 1<<40),
+	}
+	methods[0].itemAddrR, methods[0].requestAmnt, methods[0].nonce, methods[0].txnCtr = "b", 7, 4, 9
+	for i := range methods {
+		methods[i].process = 2
+	}
+	path := filepath.Join(t.TempDir(), "log")
+	writeBinlog(t, path, 2, 99, methods)
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	h, got, torn, err := readBinlog(f)
+	if err != nil || torn != 0 {
+		t.Fatalf("torn %d, %v", torn, err)
+	}
+	if want := (binlogHeader{LINEARIZABILITY, FIFO, 2, 99}); h != want {
+		t.Errorf("header %+v, want %+v", h, want)
+	}
+	if !reflect.DeepEqual(got, methods) {
+		t.Errorf("read back\n%+v\nwant\n%+v", got, methods)
+	}
+}
+
+func TestBinlogDamage(t *testing.T) {
+	methods := []Method{
+		op(PRODUCER, FIFO, "a", true, 0, 1),
+		op(PRODUCER, FIFO, "b", true, 2, 3),
+		op(CONSUMER, FIFO, "a", true, 4, 5),
+	}
+	path := filepath.Join(t.TempDir(), "log")
+	writeBinlog(t, path, 0, 0, methods)
+	full, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a short record: one byte of length, the payload and its checksum
+	recLen := len(encodeMethod(nil, &methods[2])) + 1 + 4
+
+	// a crash can cut the last record anywhere
+	for cut := 1; cut < recLen; cut++ {
+		_, got, torn, err := readBinlog(bytes.NewReader(full[:len(full)-cut]))
+		if err != nil {
+			t.Fatalf("cut %d: %v", cut, err)
+		}
+		if len(got) != 2 || torn != int64(recLen-cut) {
+			t.Errorf("cut %d: %d methods, %d bytes torn", cut, len(got), torn)
+		}
+	}
+
+	// a last record failing its checksum is torn as well
+	damaged := bytes.Clone(full)
+	damaged[len(damaged)-5] ^= 0xff
+	if _, got, torn, err := readBinlog(bytes.NewReader(damaged)); err != nil || len(got) != 2 || torn != int64(recLen) {
+		t.Errorf("bad last checksum: %d methods, %d torn, %v", len(got), torn, err)
+	}
+
+	// but not the records before it
+	damaged = bytes.Clone(full)
+	damaged[len(damaged)-recLen-5] ^= 0xff
+	if _, _, _, err := readBinlog(bytes.NewReader(damaged)); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("bad middle checksum read as %v", err)
+	}
+
+	for name, b := range map[string][]byte{
+		"magic":   append([]byte("XLOG"), full[4:]...),
+		"header":  append(append([]byte{}, full[:6]...), append([]byte{full[6] ^ 1}, full[7:]...)...),
+		"empty":   nil,
+		"version": append(append([]byte("VLOG"), 9), full[5:]...),
+	} {
+		if _, _, _, err := readBinlog(bytes.NewReader(b)); err == nil {
+			t.Errorf("%s damage accepted", name)
+		}
+	}
+}
+
+func TestCheckBinlogDir(t *testing.T) {
+	dir := t.TempDir()
+	writeBinlog(t, binlogPath(dir, 0), 0, 5, []Method{
+		op(PRODUCER, FIFO, "a", true, 0, 1),
+		op(CONSUMER, FIFO, "b", true, 6, 7),
+	})
+	writeBinlog(t, binlogPath(dir, 1), 1, 5, []Method{
+		op(PRODUCER, FIFO, "b", true, 2, 3),
+		op(CONSUMER, FIFO, "a", true, 4, 5),
+	})
+
+	h, methods, err := readBinlogDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.condition != LINEARIZABILITY || h.start != 5 || len(methods) != 4 || methods[2].process != 1 {
+		t.Fatalf("%+v, %+v", h, methods)
+	}
+	if v := verifyHistory(methods); !v.correct {
+		t.Errorf("offline verdict %+v", v)
+	}
+
+	writeBinlog(t, binlogPath(dir, 2), 2, 6, nil)
+	if _, _, err := readBinlogDir(dir); err == nil {
+		t.Error("logs of two runs read as one")
+	}
+}
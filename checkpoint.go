@@ -0,0 +1,331 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golang-collections/collections/stack"
+)
+
+// verifierState is what verifyBlocks carries from one checkpoint to the
+// next, saved so that checking a run's logs can stop and pick up again
+type verifierState struct {
+	Run           int64         `json:"run"` // start of the logged run
+	Condition     string        `json:"condition"`
+	Relax         string        `json:"relax,omitempty"`
+	Positions     []int         `json:"positions"` // methods verified from each thread's log
+	Methods       []methodState `json:"methods"`   // the verified methods, in the order verified
+	Items         []itemState   `json:"items"`
+	ItStart       int           `json:"it_start"`
+	CountIterated uint64        `json:"count_iterated"`
+	Outcome       bool          `json:"outcome"`
+	Violations    []string      `json:"violations,omitempty"`
+	RankErrors    []int         `json:"rank_errors,omitempty"`
+	Blocks        []blockState  `json:"blocks"` // the verified blocks
+}
+
+type methodState struct {
+	methodRecord
+	ID     int   `json:"id"`
+	TxnCtr int32 `json:"txn_ctr,omitempty"`
+}
+
+// fraction is one of an item's sums, which is always numerator/denominator
+type fraction struct {
+	Numerator   int64   `json:"numerator"`
+	Denominator int64   `json:"denominator"`
+	Exponent    float64 `json:"exponent"`
+}
+
+// itemState is an Item with the methods it points at as indexes into the
+// verified methods
+type itemState struct {
+	Key      string   `json:"key"`
+	Value    int      `json:"value"`
+	Status   Status   `json:"status"`
+	Sum      fraction `json:"sum"`
+	SumF     fraction `json:"sum_f"`
+	SumR     fraction `json:"sum_r"`
+	Producer int      `json:"producer"`
+	Consumer int      `json:"consumer"`
+	Promote  []int    `json:"promote,omitempty"` // promoteItems, bottom of the stack first
+	Demote   []int    `json:"demote,omitempty"`
+	Failed   []int    `json:"failed,omitempty"` // failed consumers still pending
+	Read     []int    `json:"read,omitempty"`   // reads still pending
+}
+
+type blockState struct {
+	Start      int64    `json:"start"`
+	Finish     int64    `json:"finish"`
+	Methods    int      `json:"methods"`
+	Correct    bool     `json:"correct"`
+	Violations []string `json:"violations,omitempty"`
+}
+
+// stackInts lists the indexes on s, bottom first, and leaves s as it was
+func stackInts(s *stack.Stack) []int {
+	values := make([]int, s.Len())
+	for i := len(values) - 1; i >= 0; i-- {
+		values[i] = s.Pop().(int)
+	}
+	for _, v := range values {
+		s.Push(v)
+	}
+	return values
+}
+
+// snapshot captures the verifier after the first verified methods
+func snapshot(run int64, methods []Method, verified int, items []Item, itStart int, countIterated uint64, blocks []Block) *verifierState {
+	st := &verifierState{
+		Run:           run,
+		Condition:     condition.String(),
+		Relax:         relaxationString(relaxation),
+		Methods:       make([]methodState, verified),
+		Items:         make([]itemState, len(items)),
+		ItStart:       itStart,
+		CountIterated: countIterated,
+		Outcome:       finalOutcome,
+		Violations:    finalViolations,
+		RankErrors:    rankErrors,
+	}
+
+	index := make(map[*Method]int, verified)
+	for i := range methods[:verified] {
+		m := &methods[i]
+		index[m] = i
+		st.Methods[i] = methodState{toRecord(m), m.id, m.txnCtr}
+		for len(st.Positions) <= m.process {
+			st.Positions = append(st.Positions, 0)
+		}
+		st.Positions[m.process]++
+	}
+	indexes := func(ms []*Method) []int {
+		var is []int
+		for _, m := range ms {
+			is = append(is, index[m])
+		}
+		return is
+	}
+
+	for i := range items {
+		it := &items[i]
+		st.Items[i] = itemState{
+			Key:      it.key,
+			Value:    it.value,
+			Status:   it.status,
+			Sum:      fraction{it.numerator, it.denominator, it.exponent},
+			SumF:     fraction{it.numeratorF, it.denominatorF, it.exponentF},
+			SumR:     fraction{it.numeratorR, it.denominatorR, it.exponentR},
+			Producer: it.producer,
+			Consumer: it.consumer,
+			Promote:  stackInts(&it.promoteItems),
+			Demote:   indexes(it.demoteMethods),
+			Failed:   indexes(it.failedMethods),
+			Read:     indexes(it.readMethods),
+		}
+	}
+
+	for i := range blocks {
+		if verified == 0 || i > methods[verified-1].quiescentPeriod {
+			break
+		}
+		b := &blocks[i]
+		st.Blocks = append(st.Blocks, blockState{b.start, b.finish, b.methods, b.correct, b.violations})
+	}
+	return st
+}
+
+// resume rebuilds the verifier from st and the logged methods, skipping the
+// ones st has verified already
+func (st *verifierState) resume(logged []Method) ([]Method, []Item, []Block, error) {
+	seen := make([]int, len(st.Positions))
+	var rest []Method
+	for _, m := range logged {
+		if m.process < len(seen) && seen[m.process] < st.Positions[m.process] {
+			seen[m.process]++
+			continue
+		}
+		rest = append(rest, m)
+	}
+	for t := range seen {
+		if seen[t] != st.Positions[t] {
+			return nil, nil, nil, fmt.Errorf("thread %d logged %d methods, the state verified %d", t, seen[t], st.Positions[t])
+		}
+	}
+
+	// allocated once, items point into it
+	methods := make([]Method, len(st.Methods), len(st.Methods)+len(rest))
+	for i, ms := range st.Methods {
+		m, err := fromRecord(ms.methodRecord)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("method %d: %v", i, err)
+		}
+		m.id, m.senderID, m.txnCtr = ms.ID, ms.ID, ms.TxnCtr
+		methods[i] = m
+	}
+	methods = append(methods, rest...)
+
+	pointers := func(is []int) ([]*Method, error) {
+		var ms []*Method
+		for _, i := range is {
+			if i < 0 || i >= len(st.Methods) {
+				return nil, fmt.Errorf("method %d out of range", i)
+			}
+			ms = append(ms, &methods[i])
+		}
+		return ms, nil
+	}
+	items := make([]Item, len(st.Items), len(st.Items)+len(rest))
+	for i, is := range st.Items {
+		it := &items[i]
+		it.setItemKV(is.Key, is.Value)
+		it.status = is.Status
+		it.numerator, it.denominator, it.exponent = is.Sum.Numerator, is.Sum.Denominator, is.Sum.Exponent
+		it.numeratorF, it.denominatorF, it.exponentF = is.SumF.Numerator, is.SumF.Denominator, is.SumF.Exponent
+		it.numeratorR, it.denominatorR, it.exponentR = is.SumR.Numerator, is.SumR.Denominator, is.SumR.Exponent
+		it.sum = float64(it.numerator) / float64(it.denominator)
+		it.sumF = float64(it.numeratorF) / float64(it.denominatorF)
+		it.sumR = float64(it.numeratorR) / float64(it.denominatorR)
+		it.producer, it.consumer = is.Producer, is.Consumer
+		for _, p := range is.Promote {
+			it.promoteItems.Push(p)
+		}
+		var err error
+		if it.demoteMethods, err = pointers(is.Demote); err == nil {
+			if it.failedMethods, err = pointers(is.Failed); err == nil {
+				it.readMethods, err = pointers(is.Read)
+			}
+		}
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("item %s: %v", is.Key, err)
+		}
+	}
+	for i := range rest {
+		if findItem(items, rest[i].itemAddrS) == -1 {
+			var item Item
+			item.setItem(rest[i].itemAddrS)
+			items = append(items, item)
+		}
+	}
+
+	blocks := detectBlocks(methods)
+	for i, bs := range st.Blocks {
+		if i >= len(blocks) || blocks[i].start != bs.Start || blocks[i].finish != bs.Finish {
+			return nil, nil, nil, fmt.Errorf("block %d of the state is not in the logs", i)
+		}
+		blocks[i].methods, blocks[i].correct, blocks[i].violations = bs.Methods, bs.Correct, bs.Violations
+	}
+	return methods, items, blocks, nil
+}
+
+// write replaces the state at path in one rename, so an interrupted write
+// leaves the previous state behind
+func (st *verifierState) write(path string) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := json.NewEncoder(f).Encode(st); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// loadState reads the state at path, nil if there is none yet
+func loadState(path string) (*verifierState, error) {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	st := new(verifierState)
+	if err := json.Unmarshal(b, st); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return st, nil
+}
+
+// verifyLogs verifies the methods logged by the run h describes. With a
+// state path it resumes from the state there, if any, and saves the state
+// again at the first checkpoint after every interval and at the end. Stopped
+// by ctx, it leaves the state it saved last.
+func verifyLogs(ctx context.Context, h binlogHeader, logged []Method, statePath string, every time.Duration) (Verdict, error) {
+	var st *verifierState
+	if statePath != "" {
+		var err error
+		if st, err = loadState(statePath); err != nil {
+			return Verdict{}, err
+		}
+	}
+
+	var methods []Method
+	var items []Item
+	var blocks []Block
+	var itStart int
+	var countIterated uint64
+	if st == nil {
+		methods = logged
+		items = make([]Item, 0, len(methods))
+		for i := range methods {
+			if findItem(items, methods[i].itemAddrS) == -1 {
+				var item Item
+				item.setItem(methods[i].itemAddrS)
+				items = append(items, item)
+			}
+		}
+		blocks = detectBlocks(methods)
+		finalOutcome = true
+		finalViolations = nil
+		rankErrors = nil
+	} else {
+		if st.Run != h.start {
+			return Verdict{}, fmt.Errorf("%s is the state of another run", statePath)
+		}
+		if st.Condition != condition.String() {
+			return Verdict{}, fmt.Errorf("%s was verified under %s, not %v", statePath, st.Condition, condition)
+		}
+		if st.Relax != relaxationString(relaxation) {
+			return Verdict{}, fmt.Errorf("%s was verified with -relax %q, not %q", statePath, st.Relax, relaxationString(relaxation))
+		}
+		var err error
+		if methods, items, blocks, err = st.resume(logged); err != nil {
+			return Verdict{}, fmt.Errorf("%s: %v", statePath, err)
+		}
+		itStart, countIterated = st.ItStart, st.CountIterated
+		finalOutcome, finalViolations, rankErrors = st.Outcome, st.Violations, st.RankErrors
+		fmt.Printf("Resuming after %d of %d methods\n", len(st.Methods), len(methods))
+	}
+
+	var saveErr error
+	lastSave := time.Now()
+	save := func(verified int) {
+		if saveErr != nil || (verified < len(methods) && time.Since(lastSave) < every) {
+			return
+		}
+		saveErr = snapshot(h.start, methods, verified, items, itStart, countIterated, blocks).write(statePath)
+		lastSave = time.Now()
+	}
+	if statePath == "" {
+		save = nil
+	}
+	verified, stopped := verifyBlocks(ctx, methods, items, &itStart, &countIterated, blocks, save)
+	if saveErr != nil {
+		return Verdict{}, fmt.Errorf("saving the verifier state: %v", saveErr)
+	}
+	return Verdict{finalOutcome, finalViolations, rankErrors, blocks, verified, stopped}, nil
+}
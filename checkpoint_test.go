@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestResumeVerification(t *testing.T) {
+	// b is consumed ahead of a, and a consumer fails with both present
+	history := func() []Method {
+		return []Method{
+			onThread(0, op(PRODUCER, FIFO, "a", true, 0, 1)),
+			onThread(0, op(PRODUCER, FIFO, "b", true, 2, 3)),
+			onThread(0, op(CONSUMER, FIFO, "b", true, 6, 7)),
+			onThread(0, op(CONSUMER, FIFO, "a", true, 8, 9)),
+			onThread(1, op(CONSUMER, FIFO, "", false, 4, 5)),
+			onThread(1, op(PRODUCER, FIFO, "c", true, 10, 11)),
+			onThread(1, op(CONSUMER, FIFO, "c", true, 12, 13)),
+		}
+	}
+	want := verifyHistory(history())
+	if want.correct {
+		t.Fatal("history verified correct")
+	}
+
+	// a check of the run's logs stopped when they held the first five methods
+	path := filepath.Join(t.TempDir(), "state")
+	h := binlogHeader{condition: LINEARIZABILITY, start: 42}
+	full := history()
+	partial := []Method{full[0], full[1], full[4]}
+	if _, err := verifyLogs(context.Background(), h, partial, path, 0); err != nil {
+		t.Fatal(err)
+	}
+	st, err := loadState(path)
+	if err != nil || st == nil {
+		t.Fatalf("state %v, %v", st, err)
+	}
+	if !reflect.DeepEqual(st.Positions, []int{2, 1}) || len(st.Methods) != 3 {
+		t.Errorf("positions %v after %d methods", st.Positions, len(st.Methods))
+	}
+	if len(st.Items[0].Failed) == 0 && len(st.Items[1].Failed) == 0 {
+		t.Errorf("pending failed consumer lost: %+v", st.Items)
+	}
+
+	got, err := verifyLogs(context.Background(), h, history(), path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.correct != want.correct || !reflect.DeepEqual(got.violations, want.violations) ||
+		!reflect.DeepEqual(got.rankErrors, want.rankErrors) {
+		t.Errorf("resumed to %+v, want %+v", got, want)
+	}
+	for i := range want.blocks {
+		w, g := want.blocks[i], got.blocks[i]
+		if g.methods != w.methods || g.correct != w.correct || !reflect.DeepEqual(g.violations, w.violations) {
+			t.Errorf("block %d resumed to %+v, want %+v", i, g, w)
+		}
+	}
+
+	// checking again finds everything verified
+	again, err := verifyLogs(context.Background(), h, history(), path, 0)
+	if err != nil || again.correct != want.correct || !reflect.DeepEqual(again.violations, want.violations) {
+		t.Errorf("rechecked to %+v, %v", again, err)
+	}
+}
+
+func TestResumeMismatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state")
+	methods := []Method{
+		onThread(0, op(PRODUCER, FIFO, "a", true, 0, 1)),
+		onThread(1, op(CONSUMER, FIFO, "a", true, 2, 3)),
+	}
+	h := binlogHeader{condition: LINEARIZABILITY, start: 1}
+	if _, err := verifyLogs(context.Background(), h, append([]Method(nil), methods...), path, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := verifyLogs(context.Background(), binlogHeader{start: 2}, methods, path, 0); err == nil {
+		t.Error("state of another run accepted")
+	}
+	if _, err := verifyLogs(context.Background(), h, methods[:1], path, 0); err == nil {
+		t.Error("logs shorter than the state accepted")
+	}
+	condition = SEQUENTIAL
+	defer func() { condition = LINEARIZABILITY }()
+	if _, err := verifyLogs(context.Background(), h, methods, path, 0); err == nil {
+		t.Error("state verified under another condition accepted")
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifyLogs(context.Background(), h, methods, path, 0); err == nil {
+		t.Error("damaged state accepted")
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// the subset of EDN that Jepsen writes: maps, vectors, lists, sets,
+// keywords, symbols, strings, numbers, nil and booleans. Tagged literals
+// are read as their value.
+type ednKeyword string
+type ednSymbol string
+type ednSet []interface{}
+type ednMap map[interface{}]interface{}
+
+// what a #_ form reads as, dropped by whatever contains it
+type ednDiscard struct{}
+
+type ednReader struct {
+	r    *bufio.Reader
+	line int
+}
+
+func newEDNReader(r io.Reader) *ednReader {
+	return &ednReader{r: bufio.NewReader(r), line: 1}
+}
+
+func (e *ednReader) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("edn line %d: %s", e.line, fmt.Sprintf(format, args...))
+}
+
+func (e *ednReader) readRune() (rune, error) {
+	c, _, err := e.r.ReadRune()
+	if c == '\n' {
+		e.line++
+	}
+	return c, err
+}
+
+func (e *ednReader) unreadRune(c rune) {
+	_ = e.r.UnreadRune()
+	if c == '\n' {
+		e.line--
+	}
+}
+
+func isEDNSpace(c rune) bool {
+	return unicode.IsSpace(c) || c == ','
+}
+
+func isEDNDelim(c rune) bool {
+	return isEDNSpace(c) || strings.ContainsRune("()[]{}\"';", c)
+}
+
+// skip whitespace, commas and comments, returning the next rune
+func (e *ednReader) next() (rune, error) {
+	for {
+		c, err := e.readRune()
+		if err != nil {
+			return 0, err
+		}
+		if c == ';' {
+			for c != '\n' {
+				if c, err = e.readRune(); err != nil {
+					return 0, err
+				}
+			}
+			continue
+		}
+		if !isEDNSpace(c) {
+			return c, nil
+		}
+	}
+}
+
+// Read returns the next top level value, or io.EOF
+func (e *ednReader) Read() (interface{}, error) {
+	for {
+		c, err := e.next()
+		if err != nil {
+			return nil, err
+		}
+		v, err := e.value(c)
+		if _, ok := v.(ednDiscard); !ok || err != nil {
+			return v, err
+		}
+	}
+}
+
+func (e *ednReader) value(c rune) (interface{}, error) {
+	switch c {
+	case '{':
+		vs, err := e.seq('}')
+		if err != nil {
+			return nil, err
+		}
+		if len(vs)%2 != 0 {
+			return nil, e.errorf("map with an odd number of forms")
+		}
+		m := make(ednMap, len(vs)/2)
+		for i := 0; i < len(vs); i += 2 {
+			k := vs[i]
+			switch k.(type) {
+			case []interface{}, ednSet, ednMap:
+				// unhashable, keyed by how it prints
+				k = fmt.Sprint(k)
+			}
+			m[k] = vs[i+1]
+		}
+		return m, nil
+	case '[':
+		return e.seq(']')
+	case '(':
+		return e.seq(')')
+	case '"':
+		return e.str()
+	case '\\':
+		tok, err := e.token()
+		if err != nil {
+			return nil, err
+		}
+		switch tok {
+		case "newline":
+			return "\n", nil
+		case "space":
+			return " ", nil
+		case "tab":
+			return "\t", nil
+		}
+		return tok, nil
+	case '#':
+		d, err := e.readRune()
+		if err != nil {
+			return nil, e.errorf("dispatch at end of input")
+		}
+		switch d {
+		case '{':
+			vs, err := e.seq('}')
+			return ednSet(vs), err
+		case '_':
+			_, err := e.Read()
+			return ednDiscard{}, err
+		}
+		e.unreadRune(d)
+		if _, err := e.token(); err != nil {
+			return nil, err
+		}
+		return e.Read()
+	case ')', ']', '}':
+		return nil, e.errorf("unexpected %q", c)
+	}
+
+	e.unreadRune(c)
+	tok, err := e.token()
+	if err != nil {
+		return nil, err
+	}
+	return e.atom(tok)
+}
+
+func (e *ednReader) seq(close rune) ([]interface{}, error) {
+	vs := []interface{}{}
+	for {
+		c, err := e.next()
+		if err == io.EOF {
+			return nil, e.errorf("missing %q", close)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if c == close {
+			return vs, nil
+		}
+		v, err := e.value(c)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := v.(ednDiscard); !ok {
+			vs = append(vs, v)
+		}
+	}
+}
+
+func (e *ednReader) str() (string, error) {
+	var b strings.Builder
+	for {
+		c, err := e.readRune()
+		if err != nil {
+			return "", e.errorf("unterminated string")
+		}
+		switch c {
+		case '"':
+			return b.String(), nil
+		case '\\':
+			if c, err = e.readRune(); err != nil {
+				return "", e.errorf("unterminated string")
+			}
+			switch c {
+			case 'n':
+				c = '\n'
+			case 't':
+				c = '\t'
+			case 'r':
+				c = '\r'
+			}
+		}
+		b.WriteRune(c)
+	}
+}
+
+func (e *ednReader) token() (string, error) {
+	var b strings.Builder
+	for {
+		c, err := e.readRune()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if isEDNDelim(c) {
+			e.unreadRune(c)
+			break
+		}
+		b.WriteRune(c)
+	}
+	if b.Len() == 0 {
+		return "", e.errorf("empty token")
+	}
+	return b.String(), nil
+}
+
+func (e *ednReader) atom(tok string) (interface{}, error) {
+	switch tok {
+	case "nil":
+		return nil, nil
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	if tok[0] == ':' {
+		return ednKeyword(tok[1:]), nil
+	}
+
+	c := tok[0]
+	if c >= '0' && c <= '9' || (len(tok) > 1 && (c == '-' || c == '+') && tok[1] >= '0' && tok[1] <= '9') {
+		if n, err := strconv.ParseInt(strings.TrimSuffix(tok, "N"), 10, 64); err == nil {
+			return n, nil
+		}
+		if f, err := strconv.ParseFloat(strings.TrimSuffix(tok, "M"), 64); err == nil {
+			return f, nil
+		}
+		return nil, e.errorf("bad number %q", tok)
+	}
+	return ednSymbol(tok), nil
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ethQuantity is a JSON-RPC quantity, hex with a 0x prefix or decimal,
+// quoted or not
+type ethQuantity struct {
+	big.Int
+}
+
+func (q *ethQuantity) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	switch s {
+	case "null":
+		return nil
+	case "true":
+		s = "1"
+	case "false":
+		s = "0"
+	}
+	base := 10
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		s, base = s[2:], 16
+		if s == "" {
+			s = "0"
+		}
+	}
+	if _, ok := q.SetString(s, base); !ok {
+		return fmt.Errorf("bad quantity %s", b)
+	}
+	return nil
+}
+
+// ethTx is one transaction of an exported block range. Balances, in wei,
+// are optional and keyed by address.
+type ethTx struct {
+	Hash        string                  `json:"hash"`
+	From        string                  `json:"from"`
+	To          string                  `json:"to"`
+	Value       ethQuantity             `json:"value"`
+	Nonce       ethQuantity             `json:"nonce"`
+	BlockNumber ethQuantity             `json:"blockNumber"`
+	Index       ethQuantity             `json:"transactionIndex"`
+	Status      *ethQuantity            `json:"status"`
+	Pre         map[string]*ethQuantity `json:"preBalances"`
+	Post        map[string]*ethQuantity `json:"postBalances"`
+}
+
+type ethBlock struct {
+	Number       *ethQuantity `json:"number"`
+	Transactions []ethTx      `json:"transactions"`
+}
+
+type ethTrace struct {
+	Blocks       []ethBlock `json:"blocks"`
+	Transactions []ethTx    `json:"transactions"`
+}
+
+func (t *ethTx) ok() bool {
+	return t.Status == nil || t.Status.Sign() != 0
+}
+
+func (t *ethTx) name() string {
+	if t.Hash != "" {
+		return t.Hash
+	}
+	return fmt.Sprintf("%s/%s", t.BlockNumber.String(), t.Index.String())
+}
+
+// readEthTrace reads {"blocks": [{"number", "transactions"}]},
+// {"transactions": [...]} or a bare array of transactions, and returns the
+// transactions in chain order with lower case addresses
+func readEthTrace(r io.Reader) ([]ethTx, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var trace ethTrace
+	if strings.HasPrefix(strings.TrimSpace(string(b)), "[") {
+		err = json.Unmarshal(b, &trace.Transactions)
+	} else {
+		err = json.Unmarshal(b, &trace)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	txs := trace.Transactions
+	for _, block := range trace.Blocks {
+		for _, tx := range block.Transactions {
+			if block.Number != nil {
+				tx.BlockNumber.Set(&block.Number.Int)
+			}
+			txs = append(txs, tx)
+		}
+	}
+
+	for i := range txs {
+		tx := &txs[i]
+		if tx.From == "" {
+			return nil, fmt.Errorf("transaction %s has no sender", tx.name())
+		}
+		tx.From = strings.ToLower(tx.From)
+		tx.To = strings.ToLower(tx.To)
+		for _, m := range []map[string]*ethQuantity{tx.Pre, tx.Post} {
+			for addr, q := range m {
+				if lower := strings.ToLower(addr); lower != addr {
+					delete(m, addr)
+					m[lower] = q
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(txs, func(a, b int) bool {
+		if c := txs[a].BlockNumber.Cmp(&txs[b].BlockNumber.Int); c != 0 {
+			return c < 0
+		}
+		return txs[a].Index.Cmp(&txs[b].Index.Int) < 0
+	})
+	return txs, nil
+}
+
+var gwei = big.NewInt(1e9)
+
+// toGwei is what a wei amount fits the int fields of a method as
+func toGwei(wei *big.Int) int {
+	g := new(big.Int).Quo(wei, gwei)
+	if !g.IsInt64() || g.Int64() > math.MaxInt {
+		return math.MaxInt
+	}
+	return int(g.Int64())
+}
+
+// ethHistory turns a chain into the history work would record for it: each
+// transaction is a producer and a consumer on its sender's account, the
+// transactions run one after the other and every sender is a thread. A
+// reverted transaction is still committed, with its nonce, but moves
+// nothing. Amounts and balances are in gwei.
+func ethHistory(txs []ethTx) []Method {
+	methods := make([]Method, 0, 2*len(txs))
+	senders := make(map[string]int)
+	for i := range txs {
+		tx := &txs[i]
+		process, ok := senders[tx.From]
+		if !ok {
+			process = len(senders)
+			senders[tx.From] = process
+		}
+
+		var balanceSender, balanceReceiver int
+		if q := tx.Pre[tx.From]; q != nil {
+			balanceSender = toGwei(&q.Int)
+		}
+		if q := tx.Pre[tx.To]; q != nil {
+			balanceReceiver = toGwei(&q.Int)
+		}
+		amount := 0
+		if tx.ok() {
+			amount = toGwei(&tx.Value.Int)
+		}
+		tID := int32(tx.Index.Int64())
+
+		var m1, m2 Method
+		m1.setMethod(len(methods), tx.From, tx.To, balanceSender, FIFO, PRODUCER, true, process, amount, tID)
+		m2.setMethod(len(methods)+1, tx.From, tx.To, balanceReceiver, FIFO, CONSUMER, true, process, -amount, tID)
+		for _, m := range []*Method{&m1, &m2} {
+			m.invocation = int64(2 * i)
+			m.response = int64(2*i + 1)
+			m.process = process
+			m.nonce = tx.Nonce.Int64()
+		}
+		methods = append(methods, m1, m2)
+	}
+	return methods
+}
+
+// checkEthLedger checks the balances a trace carries, in wei: an account
+// starts a transaction with the balance the last transaction touching it
+// left, the receiver of a successful transfer gains exactly its value and
+// the sender pays at least that, fees on top
+func checkEthLedger(txs []ethTx) []string {
+	var violations []string
+	last := make(map[string]*big.Int)
+	lastTx := make(map[string]string)
+
+	for i := range txs {
+		tx := &txs[i]
+		for _, addr := range []string{tx.From, tx.To} {
+			pre := tx.Pre[addr]
+			if pre == nil || last[addr] == nil || pre.Cmp(last[addr]) == 0 {
+				continue
+			}
+			violations = append(violations, fmt.Sprintf("%s: %s starts with %s but %s left it with %s",
+				tx.name(), addr, pre.String(), lastTx[addr], last[addr].String()))
+		}
+
+		value := new(big.Int)
+		if tx.ok() {
+			value.Set(&tx.Value.Int)
+		}
+		if tx.To != "" && tx.To != tx.From && tx.Pre[tx.To] != nil && tx.Post[tx.To] != nil {
+			gain := new(big.Int).Sub(&tx.Post[tx.To].Int, &tx.Pre[tx.To].Int)
+			if gain.Cmp(value) != 0 {
+				violations = append(violations, fmt.Sprintf("%s: %s gains %s, transfer is %s",
+					tx.name(), tx.To, gain.String(), value.String()))
+			}
+		}
+		if tx.Pre[tx.From] != nil && tx.Post[tx.From] != nil {
+			paid := new(big.Int).Sub(&tx.Pre[tx.From].Int, &tx.Post[tx.From].Int)
+			if tx.To == tx.From {
+				value.SetInt64(0)
+			}
+			if paid.Cmp(value) < 0 {
+				violations = append(violations, fmt.Sprintf("%s: %s pays %s, transfer is %s",
+					tx.name(), tx.From, paid.String(), value.String()))
+			}
+		}
+
+		for addr, post := range tx.Post {
+			last[addr] = &post.Int
+			lastTx[addr] = tx.name()
+		}
+	}
+	return violations
+}
+
+// eth verifies an exported block range:
+// verifier eth [-condition c] [-relax fifo=k] trace.json
+func eth(args []string) {
+	fs := flag.NewFlagSet("eth", flag.ExitOnError)
+	conditionFlag := fs.String("condition", "linearizability", "correctness condition: linearizability, sequential or quiescent")
+	relaxFlag := fs.String("relax", "", "allowed rank error per semantics, e.g. fifo=2,priority=1")
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		fmt.Println("usage: verifier eth [-condition c] [-relax fifo=k] trace.json")
+		os.Exit(2)
+	}
+
+	var err error
+	if condition, err = parseCondition(*conditionFlag); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if relaxation, err = parseRelaxation(*relaxFlag); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	txs, err := readEthTrace(f)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	methods := ethHistory(txs)
+	correct := reportVerdict(verifyHistory(methods))
+	for _, nv := range checkNonces(methods) {
+		// method ids count two per transaction
+		logAt(collectorLog, slog.LevelWarn, "nonce out of order", "transaction", txs[nv.method/2].name(), "sender", nv.sender, "nonce", nv.nonce, "want", nv.want)
+		correct = false
+	}
+	for _, v := range checkEthLedger(txs) {
+		logAt(collectorLog, slog.LevelWarn, "ledger violation", "violation", v)
+		correct = false
+	}
+	if !correct {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const ethTraceOK = `{"blocks": [
+{"number": "0x10", "transactions": [
+ {"hash": "0xa1", "from": "0xAAAA", "to": "0xbbbb", "value": "0x3b9aca00", "nonce": "0x7", "transactionIndex": "0x0",
+  "preBalances": {"0xaaaa": "5000000000", "0xbbbb": "0"}, "postBalances": {"0xaaaa": "3999979000", "0xbbbb": "1000000000"}},
+ {"hash": "0xa2", "from": "0xcccc", "to": "0xaaaa", "value": 10, "nonce": 0, "transactionIndex": 1, "status": "0x0"}
+]},
+{"number": 17, "transactions": [
+ {"hash": "0xa3", "from": "0xaaaa", "to": "0xbbbb", "value": "1000000000", "nonce": "0x8", "transactionIndex": "0x0",
+  "preBalances": {"0xaaaa": "3999979000", "0xbbbb": "1000000000"}, "postBalances": {"0xaaaa": "2999958000", "0xbbbb": "2000000000"}}
+]}
+]}`
+
+func TestReadEthTrace(t *testing.T) {
+	txs, err := readEthTrace(strings.NewReader(ethTraceOK))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txs) != 3 || txs[0].From != "0xaaaa" || txs[2].BlockNumber.Int64() != 17 || txs[0].Value.Int64() != 1e9 {
+		t.Fatalf("read %+v", txs)
+	}
+	if txs[1].ok() {
+		t.Errorf("reverted transaction read as successful")
+	}
+
+	// the reverted transaction moves nothing
+	methods := ethHistory(txs)
+	if len(methods) != 6 || methods[0].requestAmnt != 1 || methods[1].requestAmnt != -1 || methods[0].itemAddrR != "0xbbbb" ||
+		methods[2].requestAmnt != 0 || methods[4].nonce != 8 {
+		t.Fatalf("history %+v", methods)
+	}
+	if v := verifyHistory(methods); !v.correct {
+		t.Errorf("history violations %v", v.violations)
+	}
+	if v := checkNonces(methods); len(v) != 0 {
+		t.Errorf("nonce violations %v", v)
+	}
+	if v := checkEthLedger(txs); len(v) != 0 {
+		t.Errorf("ledger violations %v", v)
+	}
+}
+
+func TestEthNonces(t *testing.T) {
+	txs, err := readEthTrace(strings.NewReader(`[
+{"hash": "0x1", "from": "0xaa", "nonce": 3, "blockNumber": 1, "transactionIndex": 0},
+{"hash": "0x2", "from": "0xaa", "nonce": 3, "blockNumber": 1, "transactionIndex": 1},
+{"hash": "0x3", "from": "0xaa", "nonce": 5, "blockNumber": 2, "transactionIndex": 0},
+{"hash": "0x4", "from": "0xbb", "nonce": 0, "blockNumber": 2, "transactionIndex": 1}
+]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := checkNonces(ethHistory(txs))
+	if len(v) != 2 || txs[v[0].method/2].Hash != "0x2" || v[0].nonce != 3 || v[0].want != 4 ||
+		txs[v[1].method/2].Hash != "0x3" || v[1].nonce != 5 || v[1].want != 4 {
+		t.Errorf("violations %v", v)
+	}
+}
+
+func TestCheckEthLedger(t *testing.T) {
+	txs, err := readEthTrace(strings.NewReader(`[
+{"hash": "0x1", "from": "0xaa", "to": "0xbb", "value": 10, "blockNumber": 1, "transactionIndex": 0,
+ "preBalances": {"0xaa": 100, "0xbb": 0}, "postBalances": {"0xaa": 95, "0xbb": 9}},
+{"hash": "0x2", "from": "0xbb", "to": "0xcc", "value": 1, "blockNumber": 2, "transactionIndex": 0,
+ "preBalances": {"0xbb": 10, "0xcc": 0}, "postBalances": {"0xbb": 9, "0xcc": 1}}
+]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := checkEthLedger(txs)
+	// 0xbb gains 9 of 10, 0xaa pays 5 of 10, 0xbb starts 0x2 with 10 instead of 9
+	if len(v) != 3 {
+		t.Errorf("violations %q", v)
+	}
+
+	if _, err := readEthTrace(strings.NewReader(`[{"hash": "0x1", "value": "0xzz"}]`)); err == nil {
+		t.Error("bad quantity read without error")
+	}
+	if _, err := readEthTrace(strings.NewReader(`[{"hash": "0x1", "value": 1}]`)); err == nil {
+		t.Error("transaction without sender read without error")
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+// #include <stdlib.h>
+import "C"
+
+import (
+	"sync"
+)
+
+// histories pushed through the C ABI, by handle
+var exported = struct {
+	sync.Mutex
+	next      int
+	histories map[int][]Method
+	verdicts  map[int]Verdict // last verdict per handle
+}{histories: make(map[int][]Method), verdicts: make(map[int]Verdict)}
+
+func verifierNew() int {
+	exported.Lock()
+	defer exported.Unlock()
+
+	exported.next++
+	exported.histories[exported.next] = make([]Method, 0)
+	return exported.next
+}
+
+func verifierPush(handle int, m Method) bool {
+	exported.Lock()
+	defer exported.Unlock()
+
+	methods, ok := exported.histories[handle]
+	if !ok {
+		return false
+	}
+	m.id = len(methods)
+	exported.histories[handle] = append(methods, m)
+	return true
+}
+
+// verifierVerdict verifies everything pushed so far; the verifier works on
+// globals, so only one history is verified at a time
+func verifierVerdict(handle int) (Verdict, bool) {
+	exported.Lock()
+	defer exported.Unlock()
+
+	methods, ok := exported.histories[handle]
+	if !ok {
+		return Verdict{}, false
+	}
+	v := verifyHistory(append([]Method(nil), methods...))
+	exported.verdicts[handle] = v
+	return v, true
+}
+
+func verifierLastVerdict(handle int) (Verdict, bool) {
+	exported.Lock()
+	defer exported.Unlock()
+
+	v, ok := exported.verdicts[handle]
+	return v, ok
+}
+
+func verifierFree(handle int) {
+	exported.Lock()
+	defer exported.Unlock()
+
+	delete(exported.histories, handle)
+	delete(exported.verdicts, handle)
+}
+
+// VerifierNew returns a handle for a new, empty history.
+//
+//export VerifierNew
+func VerifierNew() C.int {
+	return C.int(verifierNew())
+}
+
+// VerifierPush appends one method record to a history. It returns 0, or -1
+// for an unknown handle or an out of range type or semantics.
+//
+//export VerifierPush
+func VerifierPush(handle C.int, thread C.int, types C.int, semantics C.int, key *C.char, value C.longlong,
+	invocation C.longlong, response C.longlong, status C.int) C.int {
+	if types < C.int(PRODUCER) || types > C.int(WRITER) || semantics < C.int(FIFO) || semantics > C.int(PRIORITY) {
+		return -1
+	}
+
+	var m Method
+	m.setMethod(0, C.GoString(key), "", int(value), Semantics(semantics), Types(types), status != 0, 0, 0, 0)
+	m.invocation = int64(invocation)
+	m.response = int64(response)
+	m.process = int(thread)
+
+	if !verifierPush(int(handle), m) {
+		return -1
+	}
+	return 0
+}
+
+// VerifierVerdict verifies the history and returns 1 if it is correct, 0 if
+// not, or -1 for an unknown handle.
+//
+//export VerifierVerdict
+func VerifierVerdict(handle C.int) C.int {
+	v, ok := verifierVerdict(int(handle))
+	if !ok {
+		return -1
+	}
+	if v.correct {
+		return 1
+	}
+	return 0
+}
+
+// VerifierViolation returns the key of the i-th violating item of the last
+// VerifierVerdict, or NULL. The caller frees the string.
+//
+//export VerifierViolation
+func VerifierViolation(handle C.int, i C.int) *C.char {
+	v, ok := verifierLastVerdict(int(handle))
+	if !ok || i < 0 || int(i) >= len(v.violations) {
+		return nil
+	}
+	return C.CString(v.violations[i])
+}
+
+// VerifierFree releases a history.
+//
+//export VerifierFree
+func VerifierFree(handle C.int) {
+	verifierFree(int(handle))
+}
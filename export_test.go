@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func TestExportedHistory(t *testing.T) {
+	h := verifierNew()
+	defer verifierFree(h)
+
+	verifierPush(h, op(PRODUCER, FIFO, "a", true, 0, 1))
+	verifierPush(h, op(PRODUCER, FIFO, "b", true, 2, 3))
+	verifierPush(h, op(CONSUMER, FIFO, "a", true, 4, 5))
+
+	v, ok := verifierVerdict(h)
+	if !ok || !v.correct {
+		t.Fatalf("got %+v, %v, want a correct verdict", v, ok)
+	}
+
+	verifierPush(h, op(CONSUMER, FIFO, "a", true, 6, 7))
+
+	v, _ = verifierVerdict(h)
+	if v.correct || len(v.violations) != 1 || v.violations[0] != "a" {
+		t.Fatalf("got %+v, want a violation on a", v)
+	}
+	if last, _ := verifierLastVerdict(h); last.correct != v.correct {
+		t.Errorf("last verdict %+v does not match %+v", last, v)
+	}
+}
+
+func TestExportedUnknownHandle(t *testing.T) {
+	h := verifierNew()
+	verifierFree(h)
+
+	if verifierPush(h, op(PRODUCER, FIFO, "a", true, 0, 1)) {
+		t.Error("push to a freed handle succeeded")
+	}
+	if _, ok := verifierVerdict(h); ok {
+		t.Error("verdict for a freed handle succeeded")
+	}
+}
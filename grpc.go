@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/servolino/verifier/verifierpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// grpcServer serves the same sessions as the HTTP service
+type grpcServer struct {
+	verifierpb.UnimplementedVerifierServer
+}
+
+func newGRPCServer() *grpc.Server {
+	srv := grpc.NewServer()
+	verifierpb.RegisterVerifierServer(srv, grpcServer{})
+	return srv
+}
+
+func toProto(m *Method) *verifierpb.Method {
+	return &verifierpb.Method{
+		Thread:        int32(m.process),
+		Type:          verifierpb.Types(m.types),
+		Semantics:     verifierpb.Semantics(m.semantics),
+		Key:           m.itemAddrS,
+		Receiver:      m.itemAddrR,
+		Value:         int64(m.itemBalance),
+		Invocation:    m.invocation,
+		Response:      m.response,
+		Status:        m.status,
+		RequestAmount: int64(m.requestAmnt),
+		TxnCtr:        m.txnCtr,
+		Nonce:         m.nonce,
+	}
+}
+
+func fromProto(p *verifierpb.Method) (Method, error) {
+	var m Method
+
+	if p.Type < 0 || int(p.Type) >= len(typesNames) {
+		return m, fmt.Errorf("unknown method type %d", p.Type)
+	}
+	if p.Semantics < 0 || int(p.Semantics) >= len(semanticsNames) {
+		return m, fmt.Errorf("unknown semantics %d", p.Semantics)
+	}
+	if p.Response < p.Invocation {
+		return m, fmt.Errorf("method on %q responds at %d before its invocation at %d", p.Key, p.Response, p.Invocation)
+	}
+
+	m.setMethod(0, p.Key, p.Receiver, int(p.Value), Semantics(p.Semantics), Types(p.Type), p.Status, 0, int(p.RequestAmount), p.TxnCtr)
+	m.invocation = p.Invocation
+	m.response = p.Response
+	m.process = int(p.Thread)
+	m.nonce = p.Nonce
+	return m, nil
+}
+
+// fromTransaction splits a transaction the way work does, into a producer
+// and a consumer on the sender's account
+func fromTransaction(t *verifierpb.Transaction) ([2]Method, error) {
+	var ms [2]Method
+
+	d := t.Data
+	if d == nil {
+		return ms, fmt.Errorf("transaction without data")
+	}
+	if t.Response < t.Invocation {
+		return ms, fmt.Errorf("transaction %d responds at %d before its invocation at %d", d.TId, t.Response, t.Invocation)
+	}
+
+	ms[0].setMethod(0, d.AddrSender, d.AddrReceiver, int(d.BalanceSender), FIFO, PRODUCER, t.Status, 0, int(d.Amount), d.TId)
+	ms[1].setMethod(0, d.AddrSender, d.AddrReceiver, int(d.BalanceReceiver), FIFO, CONSUMER, t.Status, 0, -int(d.Amount), d.TId)
+	for i := range ms {
+		ms[i].invocation = t.Invocation
+		ms[i].response = t.Response
+		ms[i].process = int(t.Thread)
+		ms[i].nonce = d.Nonce
+	}
+	return ms, nil
+}
+
+// fromRelaxation is the allowed rank error per semantics a session is opened with
+func fromRelaxation(ps []*verifierpb.Relaxation) ([PRIORITY + 1]int, error) {
+	var k [PRIORITY + 1]int
+	for _, p := range ps {
+		s := Semantics(p.Semantics)
+		if s != FIFO && s != LIFO && s != PRIORITY {
+			return k, fmt.Errorf("relaxation of %v: only fifo, lifo and priority relax", s)
+		}
+		if p.K < 0 {
+			return k, fmt.Errorf("relaxation of %v: negative k %d", s, p.K)
+		}
+		k[s] = int(p.K)
+	}
+	return k, nil
+}
+
+func (s *session) verdictProto(id string) *verifierpb.Verdict {
+	v := &verifierpb.Verdict{
+		Session:    id,
+		Correct:    s.verdict.correct,
+		Closed:     s.closed,
+		Methods:    int64(len(s.methods)),
+		Verified:   s.countIterated,
+		Violations: s.verdict.violations,
+	}
+	for _, r := range s.verdict.rankErrors {
+		v.RankErrors = append(v.RankErrors, int64(r))
+	}
+	for _, m := range s.counterexample() {
+		v.Counterexample = append(v.Counterexample, toProto(m))
+	}
+	return v
+}
+
+// Record opens a session on the first request and answers every request
+// with the verdict so far. A request with close set, or the client closing
+// its side, verifies the rest and ends the stream.
+func (grpcServer) Record(stream verifierpb.Verifier_RecordServer) error {
+	var id string
+	var s *session
+
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			req, err = &verifierpb.RecordRequest{Close: true}, nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if s == nil {
+			c := Condition(req.Condition)
+			if c < LINEARIZABILITY || c > QUIESCENT {
+				return status.Errorf(codes.InvalidArgument, "unknown correctness condition %d", req.Condition)
+			}
+			k, err := fromRelaxation(req.Relaxation)
+			if err != nil {
+				return status.Errorf(codes.InvalidArgument, "%v", err)
+			}
+			sessions.Lock()
+			id, s = newSession(c, k)
+			sessions.Unlock()
+		}
+
+		batch := make([]Method, 0, len(req.Methods)+2*len(req.Transactions))
+		for i, p := range req.Methods {
+			m, err := fromProto(p)
+			if err != nil {
+				return status.Errorf(codes.InvalidArgument, "method %d: %v", i, err)
+			}
+			batch = append(batch, m)
+		}
+		for i, t := range req.Transactions {
+			ms, err := fromTransaction(t)
+			if err != nil {
+				return status.Errorf(codes.InvalidArgument, "transaction %d: %v", i, err)
+			}
+			batch = append(batch, ms[:]...)
+		}
+
+		sessions.Lock()
+		if s.closed {
+			sessions.Unlock()
+			return status.Errorf(codes.FailedPrecondition, "session %s is closed", id)
+		}
+		for _, m := range batch {
+			s.add(m)
+		}
+		s.closed = req.Close
+		s.verify()
+		v := s.verdictProto(id)
+		sessions.Unlock()
+
+		if err := stream.Send(v); err != nil {
+			return err
+		}
+		if req.Close {
+			return nil
+		}
+	}
+}
+
+func (grpcServer) GetVerdict(ctx context.Context, req *verifierpb.GetVerdictRequest) (*verifierpb.Verdict, error) {
+	sessions.Lock()
+	defer sessions.Unlock()
+
+	s, ok := sessions.byID[req.Session]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "no session %s", req.Session)
+	}
+	return s.verdictProto(req.Session), nil
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/servolino/verifier/verifierpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func dialBufconn(t *testing.T) verifierpb.VerifierClient {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	srv := newGRPCServer()
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return verifierpb.NewVerifierClient(conn)
+}
+
+func pbOp(thread int32, types verifierpb.Types, key string, inv, res int64) *verifierpb.Method {
+	return &verifierpb.Method{Thread: thread, Type: types, Semantics: verifierpb.Semantics_FIFO, Key: key, Invocation: inv, Response: res, Status: true}
+}
+
+func TestGRPCRecord(t *testing.T) {
+	client := dialBufconn(t)
+	ctx := context.Background()
+
+	stream, err := client.Record(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = stream.Send(&verifierpb.RecordRequest{Methods: []*verifierpb.Method{
+		pbOp(0, verifierpb.Types_PRODUCER, "a", 0, 1),
+		pbOp(1, verifierpb.Types_PRODUCER, "b", 2, 3),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !v.Correct || v.Methods != 2 || v.Session == "" {
+		t.Fatalf("after producers: %v", v)
+	}
+
+	// b overtakes a
+	err = stream.Send(&verifierpb.RecordRequest{Methods: []*verifierpb.Method{
+		pbOp(1, verifierpb.Types_CONSUMER, "b", 4, 5),
+		pbOp(0, verifierpb.Types_CONSUMER, "a", 6, 7),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := stream.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+	v, err = stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Correct || !v.Closed || v.Verified != 4 {
+		t.Fatalf("after close: %v", v)
+	}
+	if len(v.Violations) != 1 || v.Violations[0] != "b" {
+		t.Errorf("violations %v, want [b]", v.Violations)
+	}
+	if len(v.Counterexample) != 2 {
+		t.Errorf("counterexample %v, want the two methods on b", v.Counterexample)
+	}
+
+	got, err := client.GetVerdict(ctx, &verifierpb.GetVerdictRequest{Session: v.Session})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Correct || got.Verified != 4 {
+		t.Errorf("GetVerdict %v", got)
+	}
+}
+
+func TestGRPCTransactions(t *testing.T) {
+	client := dialBufconn(t)
+
+	stream, err := client.Record(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = stream.Send(&verifierpb.RecordRequest{
+		Transactions: []*verifierpb.Transaction{
+			{Data: &verifierpb.TransactionData{AddrSender: "a", AddrReceiver: "b", Amount: 5}, Thread: 0, Invocation: 0, Response: 1, Status: true},
+			{Data: &verifierpb.TransactionData{AddrSender: "c", AddrReceiver: "a", Amount: 2}, Thread: 1, Invocation: 2, Response: 3, Status: true},
+		},
+		Close: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !v.Correct || v.Methods != 4 || v.Verified != 4 {
+		t.Errorf("verdict %v", v)
+	}
+}
+
+func TestGRPCRelaxation(t *testing.T) {
+	client := dialBufconn(t)
+
+	stream, err := client.Record(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// b overtakes a, within 1-FIFO
+	err = stream.Send(&verifierpb.RecordRequest{
+		Relaxation: []*verifierpb.Relaxation{{Semantics: verifierpb.Semantics_FIFO, K: 1}},
+		Methods: []*verifierpb.Method{
+			pbOp(0, verifierpb.Types_PRODUCER, "a", 0, 1),
+			pbOp(0, verifierpb.Types_PRODUCER, "b", 2, 3),
+			pbOp(0, verifierpb.Types_CONSUMER, "b", 4, 5),
+			pbOp(0, verifierpb.Types_CONSUMER, "a", 6, 7),
+		},
+		Close: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !v.Correct || len(v.RankErrors) < 2 || v.RankErrors[1] != 1 {
+		t.Errorf("verdict %v", v)
+	}
+}
+
+func TestGRPCErrors(t *testing.T) {
+	client := dialBufconn(t)
+	ctx := context.Background()
+
+	stream, err := client.Record(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.Send(&verifierpb.RecordRequest{Methods: []*verifierpb.Method{
+		pbOp(0, verifierpb.Types(7), "a", 0, 1),
+	}})
+	if _, err := stream.Recv(); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("bad method type: %v", err)
+	}
+
+	stream, err = client.Record(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.Send(&verifierpb.RecordRequest{Methods: []*verifierpb.Method{
+		pbOp(0, verifierpb.Types_PRODUCER, "a", 5, 1),
+	}})
+	if _, err := stream.Recv(); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("response before invocation: %v", err)
+	}
+
+	stream, err = client.Record(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.Send(&verifierpb.RecordRequest{Relaxation: []*verifierpb.Relaxation{{Semantics: verifierpb.Semantics_SET, K: 1}}})
+	if _, err := stream.Recv(); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("relaxed set: %v", err)
+	}
+
+	if _, err := client.GetVerdict(ctx, &verifierpb.GetVerdictRequest{Session: "nope"}); status.Code(err) != codes.NotFound {
+		t.Errorf("unknown session: %v", err)
+	}
+}
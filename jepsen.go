@@ -0,0 +1,304 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+)
+
+// an operation whose outcome Jepsen never learned may have taken effect at
+// any point after its invocation
+const possiblyCompleted = math.MaxInt64
+
+// jepsenOp is one invocation paired with its completion
+type jepsenOp struct {
+	process    int
+	f          ednKeyword
+	typ        ednKeyword // ok, fail or info
+	value      interface{}
+	err        interface{} // :error of a completion, if any
+	invocation int64
+	response   int64
+}
+
+// ednKey is the item key of a Jepsen value
+func ednKey(v interface{}) string {
+	switch v := v.(type) {
+	case ednKeyword:
+		return string(v)
+	case ednSymbol:
+		return string(v)
+	}
+	return fmt.Sprint(v)
+}
+
+// registerKey is the item of a register holding a value; independent keys
+// come as [k v]
+func registerKey(v interface{}) (string, bool) {
+	if kv, ok := v.([]interface{}); ok {
+		if len(kv) != 2 || kv[1] == nil {
+			return "", false
+		}
+		return ednKey(kv[0]) + "/" + ednKey(kv[1]), true
+	}
+	if v == nil {
+		return "", false
+	}
+	return ednKey(v), true
+}
+
+func ednInt(v interface{}) (int64, bool) {
+	n, ok := v.(int64)
+	return n, ok
+}
+
+// readJepsen pairs the invocations and completions of a history.edn.
+// Nemesis and other non-client operations are skipped, and invocations
+// that never completed are treated like :info.
+func readJepsen(r io.Reader) ([]jepsenOp, error) {
+	e := newEDNReader(r)
+	var ops []ednMap
+	for {
+		v, err := e.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		// a history written as one vector
+		if vs, ok := v.([]interface{}); ok {
+			for _, v := range vs {
+				if op, ok := v.(ednMap); ok {
+					ops = append(ops, op)
+				}
+			}
+			continue
+		}
+		op, ok := v.(ednMap)
+		if !ok {
+			return nil, fmt.Errorf("history entry %d is not a map", len(ops))
+		}
+		ops = append(ops, op)
+	}
+
+	var paired []jepsenOp
+	pending := make(map[int64]*jepsenOp)
+	for i, op := range ops {
+		process, ok := ednInt(op[ednKeyword("process")])
+		if !ok {
+			continue
+		}
+		typ, _ := op[ednKeyword("type")].(ednKeyword)
+		f, _ := op[ednKeyword("f")].(ednKeyword)
+		t, ok := ednInt(op[ednKeyword("time")])
+		if !ok {
+			t = int64(i)
+		}
+
+		if typ == "invoke" {
+			if pending[process] != nil {
+				return nil, fmt.Errorf("op %d: process %d invoked %s before completing %s", i, process, f, pending[process].f)
+			}
+			pending[process] = &jepsenOp{process: int(process), f: f, value: op[ednKeyword("value")], invocation: t}
+			continue
+		}
+
+		inv := pending[process]
+		if inv == nil {
+			return nil, fmt.Errorf("op %d: process %d completed %s without invoking it", i, process, f)
+		}
+		delete(pending, process)
+
+		inv.typ = typ
+		inv.response = t
+		switch typ {
+		case "ok", "fail":
+			if v, ok := op[ednKeyword("value")]; ok {
+				inv.value = v
+			}
+			inv.err = op[ednKeyword("error")]
+		case "info":
+			inv.response = possiblyCompleted
+		default:
+			return nil, fmt.Errorf("op %d: unknown type :%s", i, typ)
+		}
+		paired = append(paired, *inv)
+	}
+
+	var open []int64
+	for p := range pending {
+		open = append(open, p)
+	}
+	sort.Slice(open, func(a, b int) bool { return open[a] < open[b] })
+	for _, p := range open {
+		inv := pending[p]
+		inv.typ = "info"
+		inv.response = possiblyCompleted
+		paired = append(paired, *inv)
+	}
+	return paired, nil
+}
+
+// importJepsen maps a Jepsen history onto methods: queue ops onto producers
+// and consumers with the given semantics, set :add/:read onto SET producers
+// and readers, and register :read/:write/:cas onto MAPP readers and writers
+// keyed by value. A register item is never removed, so reads of an
+// overwritten value and failed cas are not checked.
+func importJepsen(r io.Reader, queue Semantics) ([]Method, error) {
+	ops, err := readJepsen(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var methods []Method
+	add := func(op *jepsenOp, types Types, semantics Semantics, key string, status bool) {
+		var m Method
+		m.setMethod(0, key, "", 0, semantics, types, status, 0, 0, 0)
+		m.invocation = op.invocation
+		m.response = op.response
+		m.process = op.process
+		methods = append(methods, m)
+	}
+
+	added := make(map[string]bool)
+	var setReads []*jepsenOp
+
+	for i := range ops {
+		op := &ops[i]
+		switch op.f {
+		case "enqueue":
+			if op.typ != "fail" {
+				add(op, PRODUCER, queue, ednKey(op.value), true)
+			}
+		case "dequeue":
+			switch {
+			case op.typ == "info":
+			case op.typ == "fail" && op.err != ednKeyword("empty"):
+				// did not take effect, which says nothing about the queue
+			case op.typ == "fail" || op.value == nil:
+				// the queue was empty
+				add(op, CONSUMER, queue, "", false)
+			default:
+				add(op, CONSUMER, queue, ednKey(op.value), true)
+			}
+		case "add":
+			if op.typ != "fail" {
+				add(op, PRODUCER, SET, ednKey(op.value), true)
+				added[ednKey(op.value)] = true
+			}
+		case "read":
+			if op.typ != "ok" {
+				continue
+			}
+			if elems, ok := op.value.(ednSet); ok {
+				setReads = append(setReads, op)
+				for _, v := range elems {
+					add(op, READER, SET, ednKey(v), true)
+				}
+				continue
+			}
+			if key, ok := registerKey(op.value); ok {
+				add(op, READER, MAPP, key, true)
+			}
+		case "write":
+			if key, ok := registerKey(op.value); ok && op.typ != "fail" {
+				add(op, WRITER, MAPP, key, true)
+			}
+		case "cas":
+			if op.typ == "fail" {
+				continue
+			}
+			// [from to], or [k [from to]] for independent keys
+			vs, _ := op.value.([]interface{})
+			var k interface{}
+			if len(vs) == 2 {
+				if inner, ok := vs[1].([]interface{}); ok {
+					k, vs = vs[0], inner
+				}
+			}
+			if len(vs) != 2 {
+				return nil, fmt.Errorf("process %d: cas value %v is not [from to]", op.process, op.value)
+			}
+			from, to := vs[0], vs[1]
+			if k != nil {
+				from, to = []interface{}{k, from}, []interface{}{k, to}
+			}
+			if key, ok := registerKey(from); ok && op.typ == "ok" {
+				add(op, READER, MAPP, key, true)
+			}
+			if key, ok := registerKey(to); ok {
+				add(op, WRITER, MAPP, key, true)
+			}
+		default:
+			return nil, fmt.Errorf("process %d: unsupported :f :%s", op.process, op.f)
+		}
+	}
+
+	// a set read also says every other element was absent
+	var keys []string
+	for key := range added {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, op := range setReads {
+		seen := make(map[string]bool)
+		for _, v := range op.value.(ednSet) {
+			seen[ednKey(v)] = true
+		}
+		for _, key := range keys {
+			if !seen[key] {
+				add(op, READER, SET, key, false)
+			}
+		}
+	}
+	return methods, nil
+}
+
+// jepsen verifies a Jepsen history:
+// verifier jepsen [-condition c] [-relax fifo=k] [-queue fifo] history.edn
+func jepsen(args []string) {
+	fs := flag.NewFlagSet("jepsen", flag.ExitOnError)
+	conditionFlag := fs.String("condition", "linearizability", "correctness condition: linearizability, sequential or quiescent")
+	relaxFlag := fs.String("relax", "", "allowed rank error per semantics, e.g. fifo=2,priority=1")
+	queueFlag := fs.String("queue", "fifo", "semantics of :enqueue/:dequeue: fifo, lifo, set or priority")
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		fmt.Println("usage: verifier jepsen [-condition c] [-relax fifo=k] [-queue fifo] history.edn")
+		os.Exit(2)
+	}
+
+	var err error
+	if condition, err = parseCondition(*conditionFlag); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if relaxation, err = parseRelaxation(*relaxFlag); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	queue, err := parseSemantics(*queueFlag)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	methods, err := importJepsen(f, queue)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if !reportVerdict(verifyHistory(methods)) {
+		os.Exit(1)
+	}
+}
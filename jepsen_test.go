@@ -0,0 +1,174 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEDNReader(t *testing.T) {
+	e := newEDNReader(strings.NewReader(`; a comment
+{:type :ok, :f :read, :value #{1 2}, :process 0, :time 12N}
+[1 -2 3.5 "s\"q" nil true #_ ignored sym (4)]
+#jepsen.history.Op {:index 3}`))
+
+	v, err := e.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := v.(ednMap)
+	if m[ednKeyword("type")] != ednKeyword("ok") || m[ednKeyword("time")] != int64(12) {
+		t.Errorf("map %v", m)
+	}
+	if !reflect.DeepEqual(m[ednKeyword("value")], ednSet{int64(1), int64(2)}) {
+		t.Errorf("set %v", m[ednKeyword("value")])
+	}
+
+	v, err = e.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{int64(1), int64(-2), 3.5, `s"q`, nil, true, ednSymbol("sym"), []interface{}{int64(4)}}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("vector %#v, want %#v", v, want)
+	}
+
+	v, err = e.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.(ednMap)[ednKeyword("index")] != int64(3) {
+		t.Errorf("tagged %v", v)
+	}
+
+	if _, err := newEDNReader(strings.NewReader(`{:a 1`)).Read(); err == nil {
+		t.Error("unterminated map read without error")
+	}
+}
+
+func TestImportJepsen(t *testing.T) {
+	tests := []struct {
+		name    string
+		history string
+		methods int
+		correct bool
+	}{
+		{"queue_fifo", `
+{:type :invoke, :f :enqueue, :value 1, :process 0, :time 0}
+{:type :ok, :f :enqueue, :value 1, :process 0, :time 1}
+{:type :invoke, :f :enqueue, :value 2, :process 1, :time 2}
+{:type :ok, :f :enqueue, :value 2, :process 1, :time 3}
+{:type :invoke, :f :dequeue, :value nil, :process 0, :time 4}
+{:type :ok, :f :dequeue, :value 1, :process 0, :time 5}
+{:type :invoke, :f :dequeue, :value nil, :process 1, :time 6}
+{:type :ok, :f :dequeue, :value 2, :process 1, :time 7}
+{:type :info, :f :start, :value nil, :process :nemesis, :time 8}`, 4, true},
+		{"queue_reorder", `
+{:type :invoke, :f :enqueue, :value 1, :process 0, :time 0}
+{:type :ok, :f :enqueue, :value 1, :process 0, :time 1}
+{:type :invoke, :f :enqueue, :value 2, :process 1, :time 2}
+{:type :ok, :f :enqueue, :value 2, :process 1, :time 3}
+{:type :invoke, :f :dequeue, :value nil, :process 0, :time 4}
+{:type :ok, :f :dequeue, :value 2, :process 0, :time 5}`, 3, false},
+		{"queue_failed_enqueue_dropped", `
+{:type :invoke, :f :enqueue, :value 1, :process 0, :time 0}
+{:type :fail, :f :enqueue, :value 1, :process 0, :time 1}
+{:type :invoke, :f :dequeue, :value nil, :process 1, :time 2}
+{:type :fail, :f :dequeue, :value nil, :process 1, :time 3}`, 0, true},
+		{"queue_fail_empty", `
+{:type :invoke, :f :enqueue, :value 1, :process 0, :time 0}
+{:type :ok, :f :enqueue, :value 1, :process 0, :time 1}
+{:type :invoke, :f :dequeue, :value nil, :process 1, :time 2}
+{:type :fail, :f :dequeue, :value nil, :process 1, :time 3, :error :empty}`, 2, false},
+		{"queue_empty_while_nonempty", `
+{:type :invoke, :f :enqueue, :value 1, :process 0, :time 0}
+{:type :ok, :f :enqueue, :value 1, :process 0, :time 1}
+{:type :invoke, :f :dequeue, :value nil, :process 1, :time 2}
+{:type :ok, :f :dequeue, :value nil, :process 1, :time 3}`, 2, false},
+		// the enqueue may have happened after the dequeue was invoked
+		{"queue_info_possibly_completed", `
+{:type :invoke, :f :enqueue, :value 1, :process 0, :time 0}
+{:type :info, :f :enqueue, :value 1, :process 0, :time 1}
+{:type :invoke, :f :dequeue, :value nil, :process 1, :time 2}
+{:type :ok, :f :dequeue, :value 1, :process 1, :time 3}`, 2, true},
+		{"queue_dequeue_never_enqueued", `
+{:type :invoke, :f :dequeue, :value nil, :process 1, :time 2}
+{:type :ok, :f :dequeue, :value 9, :process 1, :time 3}`, 1, false},
+		{"set_lost_add", `
+{:type :invoke, :f :add, :value 1, :process 0, :time 0}
+{:type :ok, :f :add, :value 1, :process 0, :time 1}
+{:type :invoke, :f :read, :value nil, :process 1, :time 2}
+{:type :ok, :f :read, :value #{}, :process 1, :time 3}`, 2, false},
+		{"set_read", `
+{:type :invoke, :f :add, :value 1, :process 0, :time 0}
+{:type :ok, :f :add, :value 1, :process 0, :time 1}
+{:type :invoke, :f :add, :value 2, :process 0, :time 2}
+{:type :invoke, :f :read, :value nil, :process 1, :time 3}
+{:type :ok, :f :read, :value #{1}, :process 1, :time 4}`, 4, true},
+		{"register_independent", `
+{:type :invoke, :f :write, :value [:x 1], :process 0, :time 0}
+{:type :ok, :f :write, :value [:x 1], :process 0, :time 1}
+{:type :invoke, :f :cas, :value [:x [1 2]], :process 1, :time 2}
+{:type :ok, :f :cas, :value [:x [1 2]], :process 1, :time 3}
+{:type :invoke, :f :read, :value [:x nil], :process 0, :time 4}
+{:type :ok, :f :read, :value [:x 2], :process 0, :time 5}`, 4, true},
+		{"register_read_unwritten", `
+{:type :invoke, :f :write, :value 1, :process 0, :time 0}
+{:type :ok, :f :write, :value 1, :process 0, :time 1}
+{:type :invoke, :f :read, :value nil, :process 1, :time 2}
+{:type :ok, :f :read, :value 3, :process 1, :time 3}`, 2, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			methods, err := importJepsen(strings.NewReader(tt.history), FIFO)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(methods) != tt.methods {
+				t.Fatalf("%d methods, want %d", len(methods), tt.methods)
+			}
+			if v := verifyHistory(methods); v.correct != tt.correct {
+				t.Errorf("correct = %v, want %v (violations %v)", v.correct, tt.correct, v.violations)
+			}
+		})
+	}
+}
+
+func TestImportJepsenFailedDequeue(t *testing.T) {
+	// dequeues that time out or are rejected while the queue holds 1 and 2
+	f, err := os.Open(filepath.Join("testdata", "jepsen_failed_dequeue.edn"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	methods, err := importJepsen(f, FIFO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range methods {
+		if m.types == CONSUMER && !m.status {
+			t.Errorf("failed dequeue at [%d, %d] taken for an empty queue", m.invocation, m.response)
+		}
+	}
+	if v := verifyHistory(methods); !v.correct {
+		t.Errorf("violations %v", v.violations)
+	}
+}
+
+func TestImportJepsenErrors(t *testing.T) {
+	for _, history := range []string{
+		`{:type :ok, :f :enqueue, :value 1, :process 0, :time 1}`,
+		`{:type :invoke, :f :enqueue, :value 1, :process 0, :time 0}
+{:type :invoke, :f :enqueue, :value 2, :process 0, :time 1}`,
+		`{:type :invoke, :f :frobnicate, :value 1, :process 0, :time 0}
+{:type :ok, :f :frobnicate, :value 1, :process 0, :time 1}`,
+		`[1 2`,
+	} {
+		if _, err := importJepsen(strings.NewReader(history), FIFO); err == nil {
+			t.Errorf("no error importing %q", history)
+		}
+	}
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// transfers a worker commits together, as one ledger block
+const ledgerBlockSize = 8
+
+// ledgerLock is held while a block commits, so a worker sees all of another
+// block's transfers or none of them
+var ledgerLock sync.Mutex
+var lastCommit int64 // commit time of the last block
+
+// blockTransfer is a transfer a worker has invoked, waiting for its block to
+// commit, and what committing it found
+type blockTransfer struct {
+	txn             TransactionData
+	invocation      int64
+	balanceSender   int
+	balanceReceiver int
+	committed       bool
+}
+
+// commitBlock applies the transfers of a ledger block to balances in order
+// and returns the block's commit time, later than that of any block before.
+// Each transfer sees the balances left by the blocks before and by the
+// transfers ahead of it, and the account only takes the transfer carrying
+// its next nonce.
+func commitBlock(block []blockTransfer) int64 {
+	ledgerLock.Lock()
+	defer ledgerLock.Unlock()
+
+	for i := range block {
+		t := &block[i]
+		t.balanceSender = balances[t.txn.addrSender]
+		t.balanceReceiver = balances[t.txn.addrReceiver]
+		if t.committed = int64(allSenders[t.txn.addrSender]) == t.txn.nonce; t.committed {
+			allSenders[t.txn.addrSender]++
+			balances[t.txn.addrSender] -= t.txn.amount
+			balances[t.txn.addrReceiver] += t.txn.amount
+		}
+	}
+
+	// the commit time is what tells the blocks apart
+	commit := time.Since(start).Nanoseconds()
+	if commit <= lastCommit {
+		commit = lastCommit + 1
+	}
+	lastCommit = commit
+	return commit
+}
+
+// ledgerKey matches the consumer work records for a transaction with its producer
+type ledgerKey struct {
+	sender     string
+	invocation int64
+	response   int64
+}
+
+// ledgerBlocks derives the ledger blocks of a history from the commits that
+// made them: the transactions of a block respond at its commit time, which
+// no other block shares, and were applied in invocation order. A transaction
+// is the producer and consumer work records for it: the producer carries the
+// balance the sender had, the consumer the balance the receiver had. A block
+// spans the first invocation of its transactions to its commit.
+func ledgerBlocks(methods []Method) []Block {
+	order := make([]int, 0, len(methods))
+	for i := range methods {
+		if methods[i].status && (methods[i].types == PRODUCER || methods[i].types == CONSUMER) {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		ma, mb := &methods[order[a]], &methods[order[b]]
+		if ma.response != mb.response {
+			return ma.response < mb.response
+		}
+		if ma.invocation != mb.invocation {
+			return ma.invocation < mb.invocation
+		}
+		// records of one transaction may come in either order
+		return ma.types == PRODUCER && mb.types != PRODUCER
+	})
+
+	var blocks []Block
+	pending := make(map[ledgerKey]int) // index of the transaction in the last block
+	for _, i := range order {
+		m := &methods[i]
+		key := ledgerKey{m.itemAddrS, m.invocation, m.response}
+
+		if m.types == PRODUCER {
+			if len(blocks) == 0 || blocks[len(blocks)-1].finish != m.response {
+				pending = make(map[ledgerKey]int)
+				var b Block
+				b.setBlock()
+				b.start = m.invocation
+				b.finish = m.response
+				blocks = append(blocks, b)
+			}
+			b := &blocks[len(blocks)-1]
+			b.txns = append(b.txns, TransactionData{
+				addrSender:    m.itemAddrS,
+				addrReceiver:  m.itemAddrR,
+				balanceSender: m.itemBalance,
+				amount:        m.requestAmnt,
+				tId:           m.txnCtr,
+				nonce:         m.nonce,
+			})
+			pending[key] = len(b.txns) - 1
+		} else {
+			// the consumer of a transaction follows its producer
+			t, ok := pending[key]
+			if !ok {
+				continue
+			}
+			blocks[len(blocks)-1].txns[t].balanceReceiver = m.itemBalance
+			delete(pending, key)
+		}
+
+		b := &blocks[len(blocks)-1]
+		b.methods++
+		if m.invocation < b.start {
+			b.start = m.invocation
+		}
+	}
+	return blocks
+}
+
+// checkLedger runs the checks verify makes on top of the checkpoints for a
+// history of transactions: nonces, and ledger blocks from the genesis
+// balances. Violating accounts are added to the final verdict.
+func checkLedger(methods []Method, genesis map[string]int) []Block {
+	for _, nv := range checkNonces(methods) {
+		logAt(collectorLog, slog.LevelWarn, "nonce out of order", "sender", nv.sender, "method", nv.method, "nonce", nv.nonce, "want", nv.want)
+		addViolation(nv.sender)
+	}
+
+	// committed transactions become visible a ledger block at a time
+	ledger := ledgerBlocks(methods)
+	for _, v := range verifyLedger(genesis, ledger, ledgerReads(methods, genesis, ledger)) {
+		logAt(collectorLog, slog.LevelWarn, "ledger violation", "violation", v)
+		finalOutcome = false
+	}
+	for itB := range ledger {
+		for _, key := range ledger[itB].violations {
+			addViolation(key)
+		}
+	}
+	return ledger
+}
+
+// ledgerReads are the readers of ledger accounts in methods, the ones of
+// other keys, like the memo registers, being left to the checkpoints
+func ledgerReads(methods []Method, genesis map[string]int, blocks []Block) []Method {
+	accounts := make(map[string]bool, len(genesis))
+	for a := range genesis {
+		accounts[a] = true
+	}
+	for itB := range blocks {
+		for _, txn := range blocks[itB].txns {
+			accounts[txn.addrSender] = true
+			accounts[txn.addrReceiver] = true
+		}
+	}
+
+	var reads []Method
+	for i := range methods {
+		if methods[i].types == READER && accounts[methods[i].itemAddrS] {
+			reads = append(reads, methods[i])
+		}
+	}
+	return reads
+}
+
+// stateRoot is a hash of the balances, the same for the same balances
+func stateRoot(balances map[string]int) string {
+	accounts := make([]string, 0, len(balances))
+	for a := range balances {
+		accounts = append(accounts, a)
+	}
+	sort.Strings(accounts)
+
+	h := sha256.New()
+	for _, a := range accounts {
+		fmt.Fprintf(h, "%s=%d\n", a, balances[a])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ledgerState is an account's balance once applied transactions of block are in
+type ledgerState struct {
+	block   int // -1 for genesis
+	applied int
+	balance int
+}
+
+// verifyLedger applies the blocks in order from the genesis balances. A
+// block commits atomically at its finish: each of its transactions has to
+// observe the balances left by the block before with the transactions ahead
+// of it in its own block applied, and a read of an account has to observe
+// the balance after some block that committed while it ran. Either seeing
+// part of another block is reported. Every block gets the snapshot of the
+// balances after it and their root, and is marked correct or not.
+func verifyLedger(genesis map[string]int, blocks []Block, reads []Method) []string {
+	balances := make(map[string]int, len(genesis))
+	history := make(map[string][]ledgerState)
+	for a, v := range genesis {
+		balances[a] = v
+		history[a] = []ledgerState{{-1, 0, v}}
+	}
+	seen := func(a string) {
+		if _, ok := history[a]; !ok {
+			history[a] = []ledgerState{{-1, 0, 0}}
+		}
+	}
+
+	// every balance an account goes through
+	for j := range blocks {
+		for p, txn := range blocks[j].txns {
+			seen(txn.addrSender)
+			seen(txn.addrReceiver)
+			balances[txn.addrSender] -= txn.amount
+			balances[txn.addrReceiver] += txn.amount
+			for _, a := range []string{txn.addrSender, txn.addrReceiver} {
+				history[a] = append(history[a], ledgerState{j, p + 1, balances[a]})
+			}
+		}
+		blocks[j].balances = make(map[string]int, len(balances))
+		for a, v := range balances {
+			blocks[j].balances[a] = v
+		}
+		blocks[j].root = stateRoot(balances)
+		blocks[j].correct = true
+		blocks[j].violations = nil
+	}
+
+	// what a balance that is not the expected one is explained by
+	explain := func(a string, observed int, block int) string {
+		for _, s := range history[a] {
+			if s.balance == observed && s.block >= 0 && s.block != block && s.applied < len(blocks[s.block].txns) {
+				return fmt.Sprintf("block %d partially applied (%d of %d transactions)", s.block, s.applied, len(blocks[s.block].txns))
+			}
+		}
+		return fmt.Sprintf("balance %d", observed)
+	}
+
+	var violations []string
+	fail := func(j int, a string, msg string) {
+		violations = append(violations, msg)
+		blocks[j].correct = false
+		for _, v := range blocks[j].violations {
+			if v == a {
+				return
+			}
+		}
+		blocks[j].violations = append(blocks[j].violations, a)
+	}
+
+	// the balance of a before transaction p of block j
+	before := func(a string, j int, p int) int {
+		hs := history[a]
+		k := len(hs) - 1
+		for hs[k].block > j || (hs[k].block == j && hs[k].applied > p) {
+			k--
+		}
+		return hs[k].balance
+	}
+
+	for j := range blocks {
+		for p, txn := range blocks[j].txns {
+			for _, o := range []struct {
+				account  string
+				observed int
+			}{{txn.addrSender, txn.balanceSender}, {txn.addrReceiver, txn.balanceReceiver}} {
+				if want := before(o.account, j, p); o.observed != want {
+					fail(j, o.account, fmt.Sprintf("block %d transaction %d sees %s of %s, expected balance %d",
+						j, p, explain(o.account, o.observed, j), o.account, want))
+				}
+			}
+		}
+	}
+
+	for _, r := range reads {
+		if r.types != READER {
+			continue
+		}
+		a := r.itemAddrS
+		seen(a)
+
+		// blocks first and last visible while the read ran
+		first, last := -1, -1
+		for j := range blocks {
+			if blocks[j].finish <= r.invocation {
+				first = j
+			}
+			if blocks[j].finish <= r.response {
+				last = j
+			}
+		}
+
+		ok := false
+		for j := first; j <= last && !ok; j++ {
+			if j == -1 {
+				ok = history[a][0].balance == r.itemBalance
+			} else {
+				ok = blocks[j].balances[a] == r.itemBalance
+			}
+		}
+		if ok {
+			continue
+		}
+		msg := fmt.Sprintf("read of %s at [%d, %d] sees %s", a, r.invocation, r.response, explain(a, r.itemBalance, -1))
+		switch {
+		case last >= 0:
+			fail(last, a, msg)
+		case len(blocks) != 0:
+			fail(0, a, msg)
+		default:
+			violations = append(violations, msg)
+		}
+	}
+	return violations
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// transfer builds the producer and consumer work records for one transaction
+func transfer(sender, receiver string, amount, balanceSender, balanceReceiver int, invocation, response int64) []Method {
+	var m1, m2 Method
+	m1.setMethod(0, sender, receiver, balanceSender, FIFO, PRODUCER, true, 0, amount, 0)
+	m2.setMethod(0, sender, receiver, balanceReceiver, FIFO, CONSUMER, true, 0, -amount, 0)
+	for _, m := range []*Method{&m1, &m2} {
+		m.invocation = invocation
+		m.response = response
+	}
+	return []Method{m1, m2}
+}
+
+func transfers(ts ...[]Method) []Method {
+	var methods []Method
+	for _, t := range ts {
+		methods = append(methods, t...)
+	}
+	return methods
+}
+
+func TestLedgerBlocks(t *testing.T) {
+	methods := transfers(
+		transfer("a", "b", 3, 10, 0, 0, 3),
+		transfer("b", "a", 1, 3, 7, 1, 3),
+		transfer("a", "b", 2, 8, 2, 4, 5),
+	)
+	blocks := ledgerBlocks(methods)
+	if len(blocks) != 2 || len(blocks[0].txns) != 2 || len(blocks[1].txns) != 1 {
+		t.Fatalf("blocks %+v", blocks)
+	}
+	if blocks[0].start != 0 || blocks[0].finish != 3 || blocks[0].methods != 4 {
+		t.Errorf("block 0 spans [%d, %d] with %d methods", blocks[0].start, blocks[0].finish, blocks[0].methods)
+	}
+	if txn := blocks[0].txns[1]; txn.addrSender != "b" || txn.balanceSender != 3 || txn.balanceReceiver != 7 || txn.amount != 1 {
+		t.Errorf("transaction %+v", txn)
+	}
+
+	if v := verifyLedger(map[string]int{"a": 10}, blocks, nil); len(v) != 0 {
+		t.Fatalf("violations %q", v)
+	}
+	if blocks[0].balances["a"] != 8 || blocks[0].balances["b"] != 2 || blocks[1].balances["a"] != 6 {
+		t.Errorf("snapshots %v %v", blocks[0].balances, blocks[1].balances)
+	}
+	if blocks[0].root != stateRoot(map[string]int{"b": 2, "a": 8}) || blocks[0].root == blocks[1].root {
+		t.Errorf("roots %s %s", blocks[0].root, blocks[1].root)
+	}
+}
+
+func TestLedgerPartialBlock(t *testing.T) {
+	// c sees a after only the first transaction of block 0
+	methods := transfers(
+		transfer("a", "b", 3, 10, 0, 0, 3),
+		transfer("a", "c", 4, 7, 0, 2, 3),
+		transfer("b", "a", 1, 3, 7, 4, 5),
+	)
+	blocks := ledgerBlocks(methods)
+	v := verifyLedger(map[string]int{"a": 10}, blocks, nil)
+	if len(v) != 1 || !strings.Contains(v[0], "block 0 partially applied (1 of 2 transactions)") {
+		t.Fatalf("violations %q", v)
+	}
+	if !blocks[0].correct || blocks[1].correct || len(blocks[1].violations) != 1 || blocks[1].violations[0] != "a" {
+		t.Errorf("block 0 correct %v, block 1 correct %v with %v", blocks[0].correct, blocks[1].correct, blocks[1].violations)
+	}
+}
+
+func TestLedgerReads(t *testing.T) {
+	methods := transfers(
+		transfer("a", "b", 3, 10, 0, 0, 3),
+		transfer("a", "c", 4, 7, 0, 2, 3),
+	)
+	blocks := ledgerBlocks(methods)
+
+	read := func(account string, balance int, invocation, response int64) Method {
+		return opValue(READER, MAPP, account, balance, true, invocation, response)
+	}
+	tests := []struct {
+		name string
+		read Method
+		ok   bool
+	}{
+		{"genesis_before_commit", read("a", 10, 1, 2), true},
+		{"overlapping_commit_old", read("a", 10, 2, 4), true},
+		{"overlapping_commit_new", read("a", 3, 2, 4), true},
+		{"after_commit", read("a", 3, 4, 5), true},
+		{"stale_after_commit", read("a", 10, 4, 5), false},
+		{"partial", read("a", 7, 2, 4), false},
+		{"future", read("a", 3, 0, 1), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := verifyLedger(map[string]int{"a": 10}, blocks, []Method{tt.read})
+			if (len(v) == 0) != tt.ok {
+				t.Errorf("violations %q", v)
+			}
+			if tt.name == "partial" && (len(v) != 1 || !strings.Contains(v[0], "partially applied")) {
+				t.Errorf("partial read reported as %q", v)
+			}
+		})
+	}
+}
+
+func TestCheckLedgerReads(t *testing.T) {
+	methods := transfers(
+		transfer("a", "b", 3, 10, 0, 0, 3),
+		transfer("a", "c", 4, 7, 0, 2, 3),
+	)
+	methods = append(methods,
+		opValue(READER, MAPP, "a", 7, true, 4, 5),      // part of the block
+		opValue(READER, MAPP, "a/memo", 9, true, 4, 5), // not an account
+	)
+
+	finalOutcome, finalViolations = true, nil
+	ledger := checkLedger(methods, map[string]int{"a": 10})
+	if finalOutcome || len(finalViolations) != 1 || finalViolations[0] != "a" {
+		t.Errorf("correct %v with violations %v", finalOutcome, finalViolations)
+	}
+	if len(ledger) != 1 || ledger[0].correct {
+		t.Errorf("ledger %+v", ledger)
+	}
+}
+
+func TestCommitBlock(t *testing.T) {
+	saved, savedSenders, savedCommit := balances, allSenders, lastCommit
+	defer func() { balances, allSenders, lastCommit = saved, savedSenders, savedCommit }()
+	balances, allSenders, lastCommit = map[string]int{"a": 10}, map[string]int{}, 0
+
+	txn := func(sender, receiver string, amount int, nonce int64) blockTransfer {
+		return blockTransfer{txn: TransactionData{addrSender: sender, addrReceiver: receiver, amount: amount, nonce: nonce}}
+	}
+	block := []blockTransfer{txn("a", "b", 3, 0), txn("a", "c", 4, 2), txn("a", "c", 4, 1)}
+	first := commitBlock(block)
+	if !block[0].committed || block[1].committed || !block[2].committed {
+		t.Errorf("committed %v %v %v", block[0].committed, block[1].committed, block[2].committed)
+	}
+	// later transfers see the earlier ones of their block
+	if block[2].balanceSender != 7 || block[2].balanceReceiver != 0 {
+		t.Errorf("third transfer saw a = %d, c = %d", block[2].balanceSender, block[2].balanceReceiver)
+	}
+	if balances["a"] != 3 || balances["b"] != 3 || balances["c"] != 4 {
+		t.Errorf("balances %v", balances)
+	}
+
+	lastCommit = first + 1000
+	if second := commitBlock([]blockTransfer{txn("b", "a", 1, 0)}); second <= first+1000 {
+		t.Errorf("second block commits at %d, after %d", second, first+1000)
+	}
+}
+
+func TestLedgerBlocksFromCommits(t *testing.T) {
+	// the second block invoked before the first committed
+	methods := transfers(
+		transfer("a", "b", 3, 10, 0, 0, 4),
+		transfer("b", "a", 1, 3, 7, 2, 4),
+		transfer("a", "b", 2, 8, 2, 3, 6),
+	)
+	blocks := ledgerBlocks(methods)
+	if len(blocks) != 2 || len(blocks[0].txns) != 2 || len(blocks[1].txns) != 1 {
+		t.Fatalf("blocks %+v", blocks)
+	}
+	if blocks[1].start != 3 || blocks[1].finish != 6 {
+		t.Errorf("block 1 spans [%d, %d]", blocks[1].start, blocks[1].finish)
+	}
+	if v := verifyLedger(map[string]int{"a": 10}, blocks, nil); len(v) != 0 {
+		t.Errorf("violations %q", v)
+	}
+}
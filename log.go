@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"strings"
+)
+
+// LevelTrace is below debug, for the traces the C++ verifier kept behind #if DEBUG_
+const LevelTrace = slog.LevelDebug - 4
+
+// levelOff turns a subsystem's logging off
+const levelOff = slog.Level(math.MaxInt32)
+
+type subsystem int
+
+const (
+	workerLog         subsystem = iota // work: the transactions each thread runs
+	collectorLog                       // verify: collecting the thread lists
+	checkpointLog                      // verifyCheckpoint and the item sums
+	failedConsumerLog                  // handleFailedConsumer
+	numSubsystems
+)
+
+var subsystemNames = [numSubsystems]string{"worker", "collector", "checkpoint", "failed-consumer"}
+
+// every subsystem has its own level, warn unless configured otherwise
+var logLevels [numSubsystems]slog.LevelVar
+var logs [numSubsystems]*slog.Logger
+
+func init() {
+	for s := range logLevels {
+		logLevels[s].Set(slog.LevelWarn)
+	}
+	setLogOutput(os.Stderr)
+	if spec := os.Getenv("VERIFIER_LOG"); spec != "" {
+		if err := configureLogging(spec); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+}
+
+func setLogOutput(w io.Writer) {
+	for s := range logs {
+		h := slog.NewTextHandler(w, &slog.HandlerOptions{Level: &logLevels[s], ReplaceAttr: replaceLevel})
+		logs[s] = slog.New(h).With("subsystem", subsystemNames[s])
+	}
+}
+
+// replaceLevel names the trace level, slog would print it as DEBUG-4
+func replaceLevel(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.LevelKey && len(groups) == 0 {
+		if l, ok := a.Value.Any().(slog.Level); ok && l <= LevelTrace {
+			a.Value = slog.StringValue("TRACE")
+		}
+	}
+	return a
+}
+
+// logging reports whether subsystem s logs at level. Hot paths check it
+// before building their log arguments, so a disabled trace costs one compare.
+func logging(s subsystem, level slog.Level) bool {
+	return level >= logLevels[s].Level()
+}
+
+// logAt logs through subsystem s when it is enabled at level
+func logAt(s subsystem, level slog.Level, msg string, args ...any) {
+	logs[s].Log(context.Background(), level, msg, args...)
+}
+
+func parseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "trace":
+		return LevelTrace, nil
+	case "off", "none":
+		return levelOff, nil
+	}
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(s)); err != nil {
+		return l, fmt.Errorf("unknown log level %q", s)
+	}
+	return l, nil
+}
+
+// configureLogging sets the levels from a spec like "info" or
+// "debug,checkpoint=trace,worker=off": a bare level applies to every
+// subsystem, subsystem=level to one
+func configureLogging(spec string) error {
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, level, scoped := strings.Cut(part, "=")
+		if !scoped {
+			level = name
+		}
+		l, err := parseLevel(level)
+		if err != nil {
+			return err
+		}
+		if !scoped {
+			for s := range logLevels {
+				logLevels[s].Set(l)
+			}
+			continue
+		}
+		found := false
+		for s, n := range subsystemNames {
+			if n == name {
+				logLevels[s].Set(l)
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown log subsystem %q, want one of %s", name, strings.Join(subsystemNames[:], ", "))
+		}
+	}
+	return nil
+}
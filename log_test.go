@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+)
+
+// resetLogging puts the levels and output back the way init left them
+func resetLogging(t *testing.T) {
+	t.Cleanup(func() {
+		for s := range logLevels {
+			logLevels[s].Set(slog.LevelWarn)
+		}
+		setLogOutput(os.Stderr)
+	})
+}
+
+func TestConfigureLogging(t *testing.T) {
+	resetLogging(t)
+
+	if err := configureLogging("info, checkpoint=trace,worker=off"); err != nil {
+		t.Fatal(err)
+	}
+	if !logging(collectorLog, slog.LevelInfo) || logging(collectorLog, slog.LevelDebug) {
+		t.Errorf("collector at %v", logLevels[collectorLog].Level())
+	}
+	if !logging(checkpointLog, LevelTrace) {
+		t.Errorf("checkpoint at %v", logLevels[checkpointLog].Level())
+	}
+	if logging(workerLog, slog.LevelError) {
+		t.Errorf("worker at %v", logLevels[workerLog].Level())
+	}
+
+	for _, spec := range []string{"loud", "checkpoint=loud", "scheduler=debug"} {
+		if err := configureLogging(spec); err == nil {
+			t.Errorf("spec %q accepted", spec)
+		}
+	}
+}
+
+func TestTraceOutput(t *testing.T) {
+	resetLogging(t)
+	var b bytes.Buffer
+	setLogOutput(&b)
+
+	if err := configureLogging("failed-consumer=trace"); err != nil {
+		t.Fatal(err)
+	}
+	methods := []Method{
+		op(PRODUCER, FIFO, "a", true, 0, 1),
+		op(CONSUMER, FIFO, "", false, 2, 3),
+	}
+	verifyHistory(methods)
+
+	out := b.String()
+	if !strings.Contains(out, "level=TRACE") || !strings.Contains(out, "subsystem=failed-consumer") ||
+		!strings.Contains(out, "item=a") {
+		t.Errorf("trace output %q", out)
+	}
+	if strings.Contains(out, "subsystem=checkpoint") {
+		t.Errorf("checkpoint logs at warn: %q", out)
+	}
+}
+
+func TestWarningOutput(t *testing.T) {
+	resetLogging(t)
+	var b bytes.Buffer
+	setLogOutput(&b)
+
+	// a spent twice, and b consumed before it was produced
+	methods := transfers(
+		transfer("a", "b", 3, 10, 0, 0, 1),
+		transfer("a", "b", 3, 7, 3, 2, 3),
+	)
+	methods[2].nonce = 0
+	methods[3].nonce = 0
+	methods = append(methods,
+		op(CONSUMER, FIFO, "c", true, 4, 5),
+		op(PRODUCER, FIFO, "c", true, 6, 7),
+	)
+	finalOutcome, finalViolations = true, nil
+	verifyHistory(methods)
+	checkLedger(methods, map[string]int{"a": 10})
+
+	out := b.String()
+	for _, want := range []string{
+		`level=WARN msg="nonce out of order" subsystem=collector sender=a`,
+		`level=WARN msg="item consumed before it was produced" subsystem=checkpoint key=c`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("no %q in %q", want, out)
+		}
+	}
+	if strings.Contains(out, "WARNING") {
+		t.Errorf("printed warnings: %q", out)
+	}
+
+	b.Reset()
+	if err := configureLogging("error"); err != nil {
+		t.Fatal(err)
+	}
+	checkLedger(methods, map[string]int{"a": 10})
+	if b.Len() != 0 {
+		t.Errorf("warnings at error level: %q", b.String())
+	}
+}
+
+func TestDisabledTraceAllocs(t *testing.T) {
+	resetLogging(t)
+	items := []Item{{key: "a"}}
+	allocs := testing.AllocsPerRun(100, func() {
+		if logging(checkpointLog, LevelTrace) {
+			logAt(checkpointLog, LevelTrace, "negative sum", "item", items[0].key, "sum", items[0].sum)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("disabled trace allocates %v times", allocs)
+	}
+}
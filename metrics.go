@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metrics of a long-running verification, served on /metrics when asked for
+var (
+	metricsRegistry = prometheus.NewRegistry()
+
+	methodsVerified = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "verifier_methods_verified_total",
+		Help: "Methods the checkpoints have applied (countIterated).",
+	})
+	checkpointsVerified = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "verifier_checkpoints_total",
+		Help: "Checkpoints completed.",
+	})
+	itemCount = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "verifier_items",
+		Help: "Items tracked by the last checkpoint.",
+	})
+	negativeSums = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "verifier_negative_sums",
+		Help: "Items with a negative sum at the end of the last checkpoint, by sum.",
+	}, []string{"sum"})
+	verificationLag = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "verifier_lag_seconds",
+		Help: "How far the last verified response is behind the workload.",
+	})
+	checkpointSeconds = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "verifier_checkpoint_duration_seconds",
+		Help:    "Time one checkpoint takes.",
+		Buckets: prometheus.ExponentialBuckets(1e-5, 4, 10),
+	})
+)
+
+// threadCollector reads threadListsSize at scrape time, so the workers do
+// not pay for the metric
+type threadCollector struct{}
+
+var threadMethodsDesc = prometheus.NewDesc("verifier_thread_methods_recorded",
+	"Methods recorded per thread (threadListsSize).", []string{"thread"}, nil)
+
+func (threadCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- threadMethodsDesc
+}
+
+func (threadCollector) Collect(ch chan<- prometheus.Metric) {
+	for i := range threadListsSize {
+		ch <- prometheus.MustNewConstMetric(threadMethodsDesc, prometheus.GaugeValue,
+			float64(threadListsSize[i].Load()), strconv.Itoa(i))
+	}
+}
+
+func init() {
+	metricsRegistry.MustRegister(threadCollector{}, methodsVerified, checkpointsVerified, itemCount,
+		negativeSums, verificationLag, checkpointSeconds)
+	for _, sum := range []string{"sum", "sum_r", "sum_f"} {
+		negativeSums.WithLabelValues(sum)
+	}
+}
+
+func metricsHandler() http.Handler {
+	return promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{})
+}
+
+// observeLag records the last verified response against the time since start
+func observeLag(lastResponse int64) {
+	verificationLag.Set(float64(time.Since(start).Nanoseconds()-lastResponse) * 1e-9)
+}
+
+// serveMetrics serves /metrics on addr in the background
+func serveMetrics(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metricsHandler())
+	fmt.Printf("Serving metrics on %s\n", addr)
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}()
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// scrape reads the samples of a /metrics page by name and labels
+func scrape(t *testing.T, url string) map[string]float64 {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("scrape status %s", resp.Status)
+	}
+
+	samples := make(map[string]float64)
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndexByte(line, ' ')
+		v, err := strconv.ParseFloat(line[i+1:], 64)
+		if err != nil {
+			t.Fatalf("sample %q: %v", line, err)
+		}
+		samples[line[:i]] = v
+	}
+	return samples
+}
+
+func TestMetricsScrape(t *testing.T) {
+	srv := httptest.NewServer(metricsHandler())
+	defer srv.Close()
+
+	before := scrape(t, srv.URL)
+	// b is consumed ahead of a
+	methods := []Method{
+		op(PRODUCER, FIFO, "a", true, 0, 1),
+		op(PRODUCER, FIFO, "b", true, 2, 3),
+		op(CONSUMER, FIFO, "b", true, 4, 5),
+		op(CONSUMER, FIFO, "a", true, 6, 7),
+	}
+	verifyHistory(methods)
+	after := scrape(t, srv.URL)
+
+	delta := func(name string) float64 { return after[name] - before[name] }
+	if d := delta("verifier_methods_verified_total"); d != 4 {
+		t.Errorf("methods verified went up by %v", d)
+	}
+	if d := delta("verifier_checkpoints_total"); d < 1 {
+		t.Errorf("checkpoints went up by %v", d)
+	}
+	// b stays negative over the later checkpoints, and is one item
+	if delta("verifier_checkpoints_total") < 2 || after[`verifier_negative_sums{sum="sum"}`] != 1 {
+		t.Errorf("%v negative sums after %v checkpoints", after[`verifier_negative_sums{sum="sum"}`], delta("verifier_checkpoints_total"))
+	}
+	if after["verifier_items"] != 2 {
+		t.Errorf("items %v", after["verifier_items"])
+	}
+	if d := delta("verifier_checkpoint_duration_seconds_count"); d != delta("verifier_checkpoints_total") {
+		t.Errorf("%v checkpoint latencies for %v checkpoints", d, delta("verifier_checkpoints_total"))
+	}
+
+	verifyHistory(methods[:2])
+	if v := scrape(t, srv.URL)[`verifier_negative_sums{sum="sum"}`]; v != 0 {
+		t.Errorf("%v negative sums after a correct history", v)
+	}
+
+	threadListsSize[3].Store(5)
+	defer threadListsSize[3].Store(0)
+	if v := scrape(t, srv.URL)[`verifier_thread_methods_recorded{thread="3"}`]; v != 5 {
+		t.Errorf("thread 3 recorded %v", v)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// nonceViolation is a committed transaction that does not carry its
+// sender's next nonce
+type nonceViolation struct {
+	sender string
+	method int // id of the producer of the transaction
+	nonce  int64
+	want   int64
+}
+
+func (v nonceViolation) String() string {
+	if v.nonce < v.want {
+		return fmt.Sprintf("%s replays nonce %d in method %d, expected %d", v.sender, v.nonce, v.method, v.want)
+	}
+	return fmt.Sprintf("%s skips to nonce %d in method %d, expected %d", v.sender, v.nonce, v.method, v.want)
+}
+
+// checkNonces checks that the committed transactions of each sender apply,
+// in response order, with gap-free and strictly increasing nonces. A
+// transaction is its producer; the first nonce of a sender is taken as
+// given, since a history may start mid-chain.
+func checkNonces(methods []Method) []nonceViolation {
+	var committed []int
+	for i := range methods {
+		if methods[i].types == PRODUCER && methods[i].status {
+			committed = append(committed, i)
+		}
+	}
+	sort.SliceStable(committed, func(a, b int) bool {
+		return methods[committed[a]].response < methods[committed[b]].response
+	})
+
+	var violations []nonceViolation
+	next := make(map[string]int64)
+	for _, i := range committed {
+		m := &methods[i]
+		if want, ok := next[m.itemAddrS]; ok && m.nonce != want {
+			violations = append(violations, nonceViolation{m.itemAddrS, m.id, m.nonce, want})
+			// a replay does not move the sender on
+			if m.nonce < want {
+				continue
+			}
+		}
+		next[m.itemAddrS] = m.nonce + 1
+	}
+	return violations
+}
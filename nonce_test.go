@@ -0,0 +1,59 @@
+package main
+
+import "testing"
+
+func txOp(types Types, sender string, nonce int64, status bool, invocation int64, response int64) Method {
+	m := op(types, FIFO, sender, status, invocation, response)
+	m.nonce = nonce
+	return m
+}
+
+func TestCheckNonces(t *testing.T) {
+	tests := []struct {
+		name    string
+		methods []Method
+		want    []nonceViolation
+	}{
+		{"in_order", []Method{
+			txOp(PRODUCER, "a", 4, true, 0, 1),
+			txOp(CONSUMER, "a", 4, true, 0, 1),
+			txOp(PRODUCER, "b", 0, true, 2, 3),
+			txOp(PRODUCER, "a", 5, true, 4, 5),
+		}, nil},
+		// a rejected transaction takes no nonce
+		{"failed_skipped", []Method{
+			txOp(PRODUCER, "a", 0, true, 0, 1),
+			txOp(PRODUCER, "a", 2, false, 2, 3),
+			txOp(PRODUCER, "a", 1, true, 4, 5),
+		}, nil},
+		{"replay", []Method{
+			txOp(PRODUCER, "a", 0, true, 0, 1),
+			txOp(PRODUCER, "a", 0, true, 2, 3),
+			txOp(PRODUCER, "a", 1, true, 4, 5),
+		}, []nonceViolation{{"a", 0, 0, 1}}},
+		{"skip", []Method{
+			txOp(PRODUCER, "a", 0, true, 0, 1),
+			txOp(PRODUCER, "a", 2, true, 2, 3),
+			txOp(PRODUCER, "a", 3, true, 4, 5),
+		}, []nonceViolation{{"a", 0, 2, 1}}},
+		// applied in response order, not in the order they were recorded
+		{"response_order", []Method{
+			txOp(PRODUCER, "a", 1, true, 0, 3),
+			txOp(PRODUCER, "a", 0, true, 1, 2),
+		}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checkNonces(tt.methods)
+			if len(got) != len(tt.want) {
+				t.Fatalf("violations %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("violation %d is %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
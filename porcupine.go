@@ -0,0 +1,396 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// porcupineOperation has the shape of porcupine.Operation, so a history
+// converts to and from []porcupine.Operation field by field and the JSON of
+// one is the JSON of the other
+type porcupineOperation struct {
+	ClientId int
+	Input    interface{}
+	Call     int64
+	Output   interface{}
+	Return   int64
+}
+
+// porcupineInput and porcupineOutput are the model input and output of a
+// method. A consumer names the item it took in its output, like a dequeue
+// returning a value.
+type porcupineInput struct {
+	Op        string `json:"op"`
+	Semantics string `json:"semantics"`
+	Key       string `json:"key,omitempty"`
+	Value     int    `json:"value,omitempty"`
+}
+
+type porcupineOutput struct {
+	Ok  bool   `json:"ok"`
+	Key string `json:"key,omitempty"`
+}
+
+// operation names porcupine models commonly use, by method type
+var porcupineOps = map[string]Types{
+	"enqueue": PRODUCER, "push": PRODUCER, "add": PRODUCER, "insert": PRODUCER, "put": PRODUCER,
+	"dequeue": CONSUMER, "pop": CONSUMER, "remove": CONSUMER, "delete": CONSUMER,
+	"read": READER, "get": READER,
+	"write": WRITER,
+}
+
+func toPorcupine(methods []Method) []porcupineOperation {
+	ops := make([]porcupineOperation, 0, len(methods))
+	for i := range methods {
+		m := &methods[i]
+		in := porcupineInput{Op: m.types.String(), Semantics: m.semantics.String(), Value: m.itemBalance}
+		out := porcupineOutput{Ok: m.status}
+		if m.types == CONSUMER {
+			out.Key = m.itemAddrS
+		} else {
+			in.Key = m.itemAddrS
+		}
+		ops = append(ops, porcupineOperation{
+			ClientId: m.process,
+			Input:    in,
+			Call:     m.invocation,
+			Output:   out,
+			Return:   m.response,
+		})
+	}
+	return ops
+}
+
+// decodeInto reads an input or output that is either already the Go type or
+// came out of JSON as a map
+func decodeInto(v interface{}, dst interface{}) error {
+	in, isInput := dst.(*porcupineInput)
+	out, isOutput := dst.(*porcupineOutput)
+	switch v := v.(type) {
+	case porcupineInput:
+		if isInput {
+			*in = v
+			return nil
+		}
+	case *porcupineInput:
+		if isInput {
+			*in = *v
+			return nil
+		}
+	case porcupineOutput:
+		if isOutput {
+			*out = v
+			return nil
+		}
+	case *porcupineOutput:
+		if isOutput {
+			*out = *v
+			return nil
+		}
+	case nil:
+		return nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, dst)
+}
+
+func fromPorcupine(ops []porcupineOperation) ([]Method, error) {
+	methods := make([]Method, 0, len(ops))
+	for i, op := range ops {
+		var in porcupineInput
+		var out porcupineOutput
+		if err := decodeInto(op.Input, &in); err != nil {
+			return nil, fmt.Errorf("operation %d input: %v", i, err)
+		}
+		if err := decodeInto(op.Output, &out); err != nil {
+			return nil, fmt.Errorf("operation %d output: %v", i, err)
+		}
+
+		types, ok := porcupineOps[strings.ToLower(in.Op)]
+		if !ok {
+			var err error
+			if types, err = parseTypes(in.Op); err != nil {
+				return nil, fmt.Errorf("operation %d: %v", i, err)
+			}
+		}
+		semantics := FIFO
+		if in.Semantics != "" {
+			var err error
+			if semantics, err = parseSemantics(in.Semantics); err != nil {
+				return nil, fmt.Errorf("operation %d: %v", i, err)
+			}
+		}
+		if op.Return < op.Call {
+			return nil, fmt.Errorf("operation %d returns at %d before its call at %d", i, op.Return, op.Call)
+		}
+
+		key := in.Key
+		if types == CONSUMER {
+			key = out.Key
+		}
+
+		var m Method
+		m.setMethod(0, key, "", in.Value, semantics, types, out.Ok, 0, 0, 0)
+		m.invocation = op.Call
+		m.response = op.Return
+		m.process = op.ClientId
+		methods = append(methods, m)
+	}
+	return methods, nil
+}
+
+// op codes of Porcupine's KV model (kvInput) and of the usual queue model
+var porcupineCodes = map[string]map[int]string{
+	"kv":    {0: "get", 1: "put", 2: "append"},
+	"queue": {0: "enqueue", 1: "dequeue"},
+}
+
+// porcupineModelInput is the input of Porcupine's KV and queue models: an
+// integer op, the key of a KV operation, and a string KV value or an
+// integer queue value
+type porcupineModelInput struct {
+	Op    int         `json:"op"`
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+// porcupineKVOutput is what a get returned, "" for a missing key
+type porcupineKVOutput struct {
+	Value string `json:"value"`
+}
+
+// porcupineModelOf tells the shape of a history from its first input: a
+// string op is the methods this package exports, an integer op with a key
+// Porcupine's KV model and without one a queue
+func porcupineModelOf(ops []porcupineOperation) string {
+	if len(ops) == 0 {
+		return "methods"
+	}
+	var in map[string]interface{}
+	b, err := json.Marshal(ops[0].Input)
+	if err != nil || json.Unmarshal(b, &in) != nil {
+		return "methods"
+	}
+
+	model := "methods"
+	for name, v := range in {
+		if _, isNumber := v.(float64); isNumber && strings.EqualFold(name, "op") {
+			model = "queue"
+		}
+	}
+	for name := range in {
+		if model == "queue" && strings.EqualFold(name, "key") {
+			return "kv"
+		}
+	}
+	return model
+}
+
+// parseOpCodes reads op codes like 0=get,1=put over the model's defaults
+func parseOpCodes(spec string, model string) (map[int]string, error) {
+	codes := make(map[int]string)
+	for code, name := range porcupineCodes[model] {
+		codes[code] = name
+	}
+	if spec == "" {
+		return codes, nil
+	}
+	for _, part := range strings.Split(spec, ",") {
+		code, name, ok := strings.Cut(strings.TrimSpace(part), "=")
+		n, err := strconv.Atoi(code)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("op code %q: want code=name", part)
+		}
+		codes[n] = strings.ToLower(name)
+	}
+	return codes, nil
+}
+
+// fromPorcupineKV turns a KV model history into MAPP readers and writers
+// keyed by key and value, the way jepsen imports registers. A get of a
+// missing key says nothing about a value and is dropped; append has no
+// value of its own to write and is refused.
+func fromPorcupineKV(ops []porcupineOperation, codes map[int]string) ([]Method, error) {
+	methods := make([]Method, 0, len(ops))
+	for i, op := range ops {
+		var in porcupineModelInput
+		var out porcupineKVOutput
+		if err := decodeInto(op.Input, &in); err != nil {
+			return nil, fmt.Errorf("operation %d input: %v", i, err)
+		}
+		if err := decodeInto(op.Output, &out); err != nil {
+			return nil, fmt.Errorf("operation %d output: %v", i, err)
+		}
+		if op.Return < op.Call {
+			return nil, fmt.Errorf("operation %d returns at %d before its call at %d", i, op.Return, op.Call)
+		}
+
+		name := codes[in.Op]
+		if name == "append" {
+			return nil, fmt.Errorf("operation %d: append is not supported, only get and put", i)
+		}
+		var value string
+		types, ok := porcupineOps[name]
+		switch {
+		case ok && types == READER:
+			value = out.Value
+		case ok && (types == WRITER || types == PRODUCER):
+			v, isString := in.Value.(string)
+			if !isString {
+				return nil, fmt.Errorf("operation %d: put of %v, want a string value", i, in.Value)
+			}
+			types, value = WRITER, v
+		default:
+			return nil, fmt.Errorf("operation %d: unknown op %d", i, in.Op)
+		}
+		if types == READER && value == "" {
+			continue
+		}
+
+		var m Method
+		m.setMethod(0, in.Key+"/"+value, "", 0, MAPP, types, true, 0, 0, 0)
+		m.invocation = op.Call
+		m.response = op.Return
+		m.process = op.ClientId
+		methods = append(methods, m)
+	}
+	return methods, nil
+}
+
+// fromPorcupineQueue turns a queue model history into FIFO producers and
+// consumers keyed by value; a dequeue that returned nothing is a failed
+// consumer
+func fromPorcupineQueue(ops []porcupineOperation, codes map[int]string) ([]Method, error) {
+	methods := make([]Method, 0, len(ops))
+	for i, op := range ops {
+		var in porcupineModelInput
+		var out *int64
+		if err := decodeInto(op.Input, &in); err != nil {
+			return nil, fmt.Errorf("operation %d input: %v", i, err)
+		}
+		if err := decodeInto(op.Output, &out); err != nil {
+			return nil, fmt.Errorf("operation %d output: %v", i, err)
+		}
+		if op.Return < op.Call {
+			return nil, fmt.Errorf("operation %d returns at %d before its call at %d", i, op.Return, op.Call)
+		}
+
+		types, ok := porcupineOps[codes[in.Op]]
+		if !ok || (types != PRODUCER && types != CONSUMER) {
+			return nil, fmt.Errorf("operation %d: unknown op %d", i, in.Op)
+		}
+
+		var m Method
+		switch types {
+		case PRODUCER:
+			v, isNumber := in.Value.(float64)
+			if !isNumber || v != math.Trunc(v) {
+				return nil, fmt.Errorf("operation %d: enqueue of %v, want an integer value", i, in.Value)
+			}
+			m.setMethod(0, strconv.FormatInt(int64(v), 10), "", 0, FIFO, PRODUCER, true, 0, 0, 0)
+		case CONSUMER:
+			if out == nil {
+				m.setMethod(0, "", "", 0, FIFO, CONSUMER, false, 0, 0, 0)
+			} else {
+				m.setMethod(0, strconv.FormatInt(*out, 10), "", 0, FIFO, CONSUMER, true, 0, 0, 0)
+			}
+		}
+		m.invocation = op.Call
+		m.response = op.Return
+		m.process = op.ClientId
+		methods = append(methods, m)
+	}
+	return methods, nil
+}
+
+// porcupine verifies a history of porcupine operations, or with -export
+// turns JSON lines of methods into one:
+// verifier porcupine [-condition c] [-relax fifo=k] [-model m] [-ops codes] history.json
+// verifier porcupine -export history.ndjson > history.json
+func porcupine(args []string) {
+	fs := flag.NewFlagSet("porcupine", flag.ExitOnError)
+	conditionFlag := fs.String("condition", "linearizability", "correctness condition: linearizability, sequential or quiescent")
+	relaxFlag := fs.String("relax", "", "allowed rank error per semantics, e.g. fifo=2,priority=1")
+	modelFlag := fs.String("model", "auto", "shape of the operations: methods, kv (Porcupine's KV model), queue or auto")
+	opsFlag := fs.String("ops", "", "names of the integer op codes, e.g. 0=get,1=put (the model's by default)")
+	export := fs.Bool("export", false, "convert JSON lines of methods into porcupine operations on stdout")
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		fmt.Println("usage: verifier porcupine [-condition c] [-relax fifo=k] [-model m] [-ops codes] [-export] file")
+		os.Exit(2)
+	}
+
+	var err error
+	if condition, err = parseCondition(*conditionFlag); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if relaxation, err = parseRelaxation(*relaxFlag); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	if *export {
+		methods, err := readRecords(f)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if err := json.NewEncoder(os.Stdout).Encode(toPorcupine(methods)); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	var ops []porcupineOperation
+	if err := json.NewDecoder(f).Decode(&ops); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	model := *modelFlag
+	if model == "auto" {
+		model = porcupineModelOf(ops)
+	}
+	var methods []Method
+	switch model {
+	case "methods":
+		methods, err = fromPorcupine(ops)
+	case "kv", "queue":
+		var codes map[int]string
+		if codes, err = parseOpCodes(*opsFlag, model); err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+		if model == "kv" {
+			methods, err = fromPorcupineKV(ops, codes)
+		} else {
+			methods, err = fromPorcupineQueue(ops, codes)
+		}
+	default:
+		fmt.Printf("unknown model %q, want methods, kv, queue or auto\n", model)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if !reportVerdict(verifyHistory(methods)) {
+		os.Exit(1)
+	}
+}
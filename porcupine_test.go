@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPorcupineRoundTrip(t *testing.T) {
+	methods := []Method{
+		op(PRODUCER, FIFO, "a", true, 0, 1),
+		opValue(PRODUCER, PRIORITY, "b", 3, true, 2, 3),
+		op(CONSUMER, FIFO, "a", true, 4, 5),
+		op(CONSUMER, FIFO, "", false, 6, 7),
+		op(WRITER, MAPP, "k", true, 8, 9),
+		op(READER, MAPP, "k", true, 10, 11),
+	}
+	for i := range methods {
+		methods[i].process = i % 2
+	}
+
+	// through Go values, as a porcupine test would hold them
+	got, err := fromPorcupine(toPorcupine(methods))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, methods) {
+		t.Errorf("round trip\n got %+v\nwant %+v", got, methods)
+	}
+
+	// and through JSON
+	b, err := json.Marshal(toPorcupine(methods))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ops []porcupineOperation
+	if err := json.Unmarshal(b, &ops); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = fromPorcupine(ops); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, methods) {
+		t.Errorf("JSON round trip\n got %+v\nwant %+v", got, methods)
+	}
+}
+
+func TestFromPorcupine(t *testing.T) {
+	// a queue history in the shape a porcupine queue model would record it
+	history := `[
+{"ClientId":0,"Input":{"op":"enqueue","key":"1"},"Call":0,"Output":{"ok":true},"Return":1},
+{"ClientId":1,"Input":{"op":"enqueue","key":"2"},"Call":2,"Output":{"ok":true},"Return":3},
+{"ClientId":0,"Input":{"op":"dequeue"},"Call":4,"Output":{"ok":true,"key":"2"},"Return":5}
+]`
+	var ops []porcupineOperation
+	if err := json.Unmarshal([]byte(history), &ops); err != nil {
+		t.Fatal(err)
+	}
+	methods, err := fromPorcupine(ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if methods[2].types != CONSUMER || methods[2].itemAddrS != "2" || methods[2].semantics != FIFO {
+		t.Errorf("dequeue became %+v", methods[2])
+	}
+	if v := verifyHistory(methods); v.correct {
+		t.Errorf("dequeue of 2 ahead of 1 verified correct")
+	}
+
+	for _, bad := range []porcupineOperation{
+		{Input: porcupineInput{Op: "frobnicate"}},
+		{Input: porcupineInput{Op: "enqueue", Semantics: "bag"}},
+		{Input: porcupineInput{Op: "enqueue"}, Call: 5, Return: 1},
+		{Input: 7},
+	} {
+		if _, err := fromPorcupine([]porcupineOperation{bad}); err == nil {
+			t.Errorf("no error converting %+v", bad)
+		}
+	}
+}
+
+func readPorcupineFixture(t *testing.T, name string) []porcupineOperation {
+	t.Helper()
+
+	b, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ops []porcupineOperation
+	if err := json.Unmarshal(b, &ops); err != nil {
+		t.Fatal(err)
+	}
+	return ops
+}
+
+func TestPorcupineKV(t *testing.T) {
+	ops := readPorcupineFixture(t, "porcupine_kv.json")
+	if model := porcupineModelOf(ops); model != "kv" {
+		t.Fatalf("model %q, want kv", model)
+	}
+	codes, _ := parseOpCodes("", "kv")
+	methods, err := fromPorcupineKV(ops, codes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the get of the missing y is dropped
+	if len(methods) != 5 {
+		t.Fatalf("%d methods, want 5: %+v", len(methods), methods)
+	}
+	if m := methods[1]; m.types != READER || m.semantics != MAPP || m.itemAddrS != "x/1" {
+		t.Errorf("get became %+v", m)
+	}
+
+	// x was never 3
+	v := verifyHistory(methods)
+	if v.correct || len(v.violations) != 1 || v.violations[0] != "x/3" {
+		t.Errorf("verdict %+v, want only x/3 violating", v)
+	}
+	if v := verifyHistory(methods[:4]); !v.correct {
+		t.Errorf("history up to the read of x/3 verdict %+v", v)
+	}
+
+	codes[1] = "append"
+	if _, err := fromPorcupineKV(ops, codes); err == nil {
+		t.Errorf("no error on append")
+	}
+}
+
+func TestPorcupineQueue(t *testing.T) {
+	ops := readPorcupineFixture(t, "porcupine_queue.json")
+	if model := porcupineModelOf(ops); model != "queue" {
+		t.Fatalf("model %q, want queue", model)
+	}
+	codes, _ := parseOpCodes("", "queue")
+	methods, err := fromPorcupineQueue(ops, codes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := methods[2]; m.types != CONSUMER || !m.status || m.itemAddrS != "2" {
+		t.Errorf("dequeue of 2 became %+v", m)
+	}
+	if m := methods[4]; m.types != CONSUMER || m.status {
+		t.Errorf("empty dequeue became %+v", m)
+	}
+
+	// 2 dequeued ahead of 1
+	if v := verifyHistory(append([]Method(nil), methods...)); v.correct {
+		t.Errorf("verdict %+v", v)
+	}
+
+	// the same history with its op codes swapped, named on the command line
+	for i := range ops {
+		in := ops[i].Input.(map[string]interface{})
+		in["Op"] = 1 - in["Op"].(float64)
+	}
+	if codes, err = parseOpCodes("0=dequeue,1=enqueue", "queue"); err != nil {
+		t.Fatal(err)
+	}
+	swapped, err := fromPorcupineQueue(ops, codes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(swapped, methods) {
+		t.Errorf("swapped op codes\n got %+v\nwant %+v", swapped, methods)
+	}
+
+	if _, err := parseOpCodes("dequeue", "queue"); err == nil {
+		t.Errorf("no error on an op code without a name")
+	}
+}
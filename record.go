@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var typesNames = []string{"producer", "consumer", "reader", "writer"}
+var semanticsNames = []string{"fifo", "lifo", "set", "map", "priority"}
+
+func (t Types) String() string {
+	if t < 0 || int(t) >= len(typesNames) {
+		return fmt.Sprintf("Types(%d)", int(t))
+	}
+	return typesNames[t]
+}
+
+func (s Semantics) String() string {
+	if s < 0 || int(s) >= len(semanticsNames) {
+		return fmt.Sprintf("Semantics(%d)", int(s))
+	}
+	return semanticsNames[s]
+}
+
+func parseTypes(s string) (Types, error) {
+	for i, name := range typesNames {
+		if strings.EqualFold(s, name) {
+			return Types(i), nil
+		}
+	}
+	return PRODUCER, fmt.Errorf("unknown method type %q", s)
+}
+
+func parseSemantics(s string) (Semantics, error) {
+	for i, name := range semanticsNames {
+		if strings.EqualFold(s, name) {
+			return Semantics(i), nil
+		}
+	}
+	return FIFO, fmt.Errorf("unknown semantics %q", s)
+}
+
+// methodRecord is how a Method is written outside the process, one JSON
+// object per method
+type methodRecord struct {
+	Thread     int    `json:"thread"`
+	Type       string `json:"type"`
+	Semantics  string `json:"semantics"`
+	Key        string `json:"key"`
+	Receiver   string `json:"receiver,omitempty"`
+	Value      int    `json:"value,omitempty"`
+	Amount     int    `json:"amount,omitempty"`
+	Invocation int64  `json:"invocation"`
+	Response   int64  `json:"response"`
+	Status     bool   `json:"status"`
+	Nonce      int64  `json:"nonce,omitempty"`
+}
+
+func toRecord(m *Method) methodRecord {
+	return methodRecord{
+		Thread:     m.process,
+		Type:       m.types.String(),
+		Semantics:  m.semantics.String(),
+		Key:        m.itemAddrS,
+		Receiver:   m.itemAddrR,
+		Value:      m.itemBalance,
+		Amount:     m.requestAmnt,
+		Invocation: m.invocation,
+		Response:   m.response,
+		Status:     m.status,
+		Nonce:      m.nonce,
+	}
+}
+
+func fromRecord(r methodRecord) (Method, error) {
+	var m Method
+
+	types, err := parseTypes(r.Type)
+	if err != nil {
+		return m, err
+	}
+	semantics, err := parseSemantics(r.Semantics)
+	if err != nil {
+		return m, err
+	}
+	if r.Response < r.Invocation {
+		return m, fmt.Errorf("method on %q responds at %d before its invocation at %d", r.Key, r.Response, r.Invocation)
+	}
+
+	m.setMethod(0, r.Key, r.Receiver, r.Value, semantics, types, r.Status, 0, r.Amount, 0)
+	m.invocation = r.Invocation
+	m.response = r.Response
+	m.process = r.Thread
+	m.nonce = r.Nonce
+	return m, nil
+}
+
+// readRecords reads JSON lines of methodRecord, as the HTTP service takes them
+func readRecords(r io.Reader) ([]Method, error) {
+	var methods []Method
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var rec methodRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		m, err := fromRecord(rec)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		methods = append(methods, m)
+	}
+	return methods, scanner.Err()
+}
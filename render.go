@@ -0,0 +1,346 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// layout of the timeline, in pixels
+const (
+	timelineLeft = 90 // room for the lane labels
+	timelineTop  = 36 // room for the time axis
+	laneHeight   = 22
+	laneGap      = 10
+)
+
+type timelineLane struct {
+	Y     float64
+	Label string
+}
+
+// timelineBand is a quiescent block behind the lanes, bad where an item
+// first failed
+type timelineBand struct {
+	X, W float64
+	Bad  bool
+	Tip  string
+}
+
+// timelineBar is a method from its invocation to its response
+type timelineBar struct {
+	X, Y, W, H float64
+	Class      string // its type, then failed and violating where they apply
+	Key        string
+	Tip        string
+}
+
+// timelineEdge joins an item's producer to a method it was demoted behind
+type timelineEdge struct {
+	X1, Y1, X2, Y2 float64
+	Key            string
+	Tip            string
+}
+
+type timelineTick struct {
+	X     float64
+	Label string
+}
+
+// timeline is a verified history laid out for timelineTemplate
+type timeline struct {
+	Title         string
+	Verdict       string
+	Correct       bool
+	Width, Height float64
+	Lanes         []timelineLane
+	Bands         []timelineBand
+	Bars          []timelineBar
+	Edges         []timelineEdge
+	Ticks         []timelineTick
+}
+
+// formatNanos writes a duration in nanoseconds in the largest unit it fills
+func formatNanos(ns int64) string {
+	switch {
+	case ns >= 1e9 || -ns >= 1e9:
+		return fmt.Sprintf("%.3gs", float64(ns)/1e9)
+	case ns >= 1e6 || -ns >= 1e6:
+		return fmt.Sprintf("%.3gms", float64(ns)/1e6)
+	case ns >= 1e3 || -ns >= 1e3:
+		return fmt.Sprintf("%.3gµs", float64(ns)/1e3)
+	}
+	return fmt.Sprintf("%dns", ns)
+}
+
+// buildTimeline lays out methods, as verifyHistoryItems left them and the
+// items it returned, over width pixels of time
+func buildTimeline(title string, methods []Method, v Verdict, items []Item, width float64) timeline {
+	t := timeline{Title: title, Correct: v.correct}
+	switch {
+	case !v.correct:
+		t.Verdict = fmt.Sprintf("not correct, violating items %v", v.violations)
+	case v.stopped != nil:
+		t.Verdict = fmt.Sprintf("correct up to method %d, stopped: %v", v.verified, v.stopped)
+	default:
+		t.Verdict = "correct"
+	}
+	if len(methods) == 0 {
+		t.Width, t.Height = timelineLeft+width, timelineTop
+		return t
+	}
+
+	lane := make(map[int]int)
+	var threads []int
+	first, last := methods[0].invocation, methods[0].response
+	for i := range methods {
+		if _, ok := lane[methods[i].process]; !ok {
+			lane[methods[i].process] = 0
+			threads = append(threads, methods[i].process)
+		}
+		if methods[i].invocation < first {
+			first = methods[i].invocation
+		}
+		if methods[i].response > last {
+			last = methods[i].response
+		}
+	}
+	sort.Ints(threads)
+	for i, thread := range threads {
+		lane[thread] = i
+		t.Lanes = append(t.Lanes, timelineLane{timelineTop + float64(i)*(laneHeight+laneGap), fmt.Sprintf("thread %d", thread)})
+	}
+	t.Width = timelineLeft + width + 10
+	t.Height = timelineTop + float64(len(threads))*(laneHeight+laneGap)
+
+	span := last - first
+	if span < 1 {
+		span = 1
+	}
+	x := func(ns int64) float64 { return timelineLeft + float64(ns-first)*width/float64(span) }
+	for i := 0; i <= 5; i++ {
+		ns := first + span*int64(i)/5
+		t.Ticks = append(t.Ticks, timelineTick{x(ns), formatNanos(ns)})
+	}
+
+	for i, b := range v.blocks {
+		band := timelineBand{X: x(b.start), W: x(b.finish) - x(b.start), Bad: len(b.violations) != 0}
+		if band.W < 1 {
+			band.W = 1
+		}
+		band.Tip = fmt.Sprintf("block %d: %s to %s, %d methods", i, formatNanos(b.start), formatNanos(b.finish), b.methods)
+		if len(b.violations) != 0 {
+			band.Tip += fmt.Sprintf(", new violations %v", b.violations)
+		}
+		t.Bands = append(t.Bands, band)
+	}
+
+	violating := make(map[string]bool)
+	for _, key := range v.violations {
+		violating[key] = true
+	}
+	item := make(map[string]*Item)
+	for i := range items {
+		item[items[i].key] = &items[i]
+	}
+
+	index := make(map[*Method]int, len(methods))
+	for i := range methods {
+		m := &methods[i]
+		index[m] = i
+
+		bar := timelineBar{X: x(m.invocation), Y: t.Lanes[lane[m.process]].Y, W: x(m.response) - x(m.invocation), H: laneHeight, Key: m.itemAddrS}
+		if bar.W < 2 {
+			bar.W = 2
+		}
+		class := []string{m.types.String()}
+		if !m.status {
+			class = append(class, "failed")
+		}
+		if violating[m.itemAddrS] {
+			class = append(class, "violating")
+		}
+		bar.Class = strings.Join(class, " ")
+
+		tip := fmt.Sprintf("%v %v %s\nthread %d, %s to %s\nstatus %v, value %d",
+			m.types, m.semantics, m.itemAddrS, m.process, formatNanos(m.invocation), formatNanos(m.response), m.status, m.itemBalance)
+		if it := item[m.itemAddrS]; it != nil {
+			tip += fmt.Sprintf("\nsum %g, sumF %g, sumR %g", it.sum, it.sumF, it.sumR)
+		}
+		bar.Tip = tip
+		t.Bars = append(t.Bars, bar)
+	}
+
+	center := func(i int) (float64, float64) {
+		b := &t.Bars[i]
+		return b.X + b.W/2, b.Y + laneHeight/2
+	}
+	for i := range items {
+		it := &items[i]
+		if len(it.demoteMethods) == 0 || it.producer >= len(methods) {
+			continue
+		}
+		x1, y1 := center(it.producer)
+		for _, ahead := range it.demoteMethods {
+			j, ok := index[ahead]
+			if !ok {
+				continue
+			}
+			x2, y2 := center(j)
+			t.Edges = append(t.Edges, timelineEdge{x1, y1, x2, y2, it.key,
+				fmt.Sprintf("%s demoted behind %s", it.key, ahead.itemAddrS)})
+		}
+	}
+	return t
+}
+
+var timelineTemplate = template.Must(template.New("timeline").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font: 13px sans-serif; margin: 16px; }
+.verdict { font-weight: bold; color: #1a7f37; }
+.verdict.bad { color: #cf222e; }
+.legend span { display: inline-block; margin-right: 14px; }
+.legend i { display: inline-block; width: 14px; height: 10px; margin-right: 4px; vertical-align: middle; }
+.band { fill: #f6f8fa; }
+.band.bad { fill: #ffebe9; }
+.lane { fill: #57606a; }
+.tick { stroke: #d0d7de; }
+.m { stroke: #24292f; stroke-width: 0.5; }
+.producer { fill: #2da44e; }
+.consumer { fill: #0969da; }
+.reader { fill: #8250df; }
+.writer { fill: #bf8700; }
+.failed { fill-opacity: 0.35; stroke-dasharray: 3 2; }
+.violating { stroke: #cf222e; stroke-width: 2.5; }
+.chain { stroke: #cf222e; stroke-width: 1.2; stroke-dasharray: 4 3; fill: none; marker-end: url(#arrow); }
+.legend .producer { background: #2da44e; }
+.legend .consumer { background: #0969da; }
+.legend .reader { background: #8250df; }
+.legend .writer { background: #bf8700; }
+.legend .failed { opacity: 0.35; }
+.legend .violating { outline: 2px solid #cf222e; }
+.dim { opacity: 0.15; }
+.hot { stroke: #000; stroke-width: 2.5; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="verdict{{if not .Correct}} bad{{end}}">{{.Verdict}}</p>
+<p class="legend">
+<span><i class="producer"></i>producer</span>
+<span><i class="consumer"></i>consumer</span>
+<span><i class="reader"></i>reader</span>
+<span><i class="writer"></i>writer</span>
+<span><i class="producer failed"></i>failed</span>
+<span><i class="producer violating"></i>violating item</span>
+<span>- - &gt; demoted behind</span>
+</p>
+<svg xmlns="http://www.w3.org/2000/svg" width="{{printf "%.0f" .Width}}" height="{{printf "%.0f" .Height}}">
+<defs><marker id="arrow" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="6" markerHeight="6" orient="auto-start-reverse"><path d="M0,0 L10,5 L0,10 z" fill="#cf222e"/></marker></defs>
+{{range .Bands}}<rect class="band{{if .Bad}} bad{{end}}" x="{{printf "%.1f" .X}}" y="20" width="{{printf "%.1f" .W}}" height="{{printf "%.0f" $.Height}}"><title>{{.Tip}}</title></rect>
+{{end}}{{range .Ticks}}<line class="tick" x1="{{printf "%.1f" .X}}" x2="{{printf "%.1f" .X}}" y1="20" y2="{{printf "%.0f" $.Height}}"/><text x="{{printf "%.1f" .X}}" y="14" text-anchor="middle">{{.Label}}</text>
+{{end}}{{range .Lanes}}<text class="lane" x="4" y="{{printf "%.1f" .Y}}" dy="15">{{.Label}}</text>
+{{end}}{{range .Bars}}<rect class="m {{.Class}}" data-key="{{.Key}}" x="{{printf "%.1f" .X}}" y="{{printf "%.1f" .Y}}" width="{{printf "%.1f" .W}}" height="{{printf "%.0f" .H}}"><title>{{.Tip}}</title></rect>
+{{end}}{{range .Edges}}<line class="chain" data-key="{{.Key}}" x1="{{printf "%.1f" .X1}}" y1="{{printf "%.1f" .Y1}}" x2="{{printf "%.1f" .X2}}" y2="{{printf "%.1f" .Y2}}"><title>{{.Tip}}</title></line>
+{{end}}</svg>
+<script>
+// hovering a method brings out every method and demotion of its item
+document.querySelectorAll("[data-key]").forEach(function (el) {
+	el.addEventListener("mouseenter", function () {
+		var key = el.getAttribute("data-key");
+		document.querySelectorAll("[data-key]").forEach(function (other) {
+			var same = other.getAttribute("data-key") === key;
+			other.classList.toggle("dim", !same);
+			other.classList.toggle("hot", same && other.tagName === "rect");
+		});
+	});
+	el.addEventListener("mouseleave", function () {
+		document.querySelectorAll("[data-key]").forEach(function (other) {
+			other.classList.remove("dim", "hot");
+		});
+	});
+});
+</script>
+</body>
+</html>
+`))
+
+func renderTimeline(w io.Writer, t timeline) error {
+	return timelineTemplate.Execute(w, t)
+}
+
+// render verifies a history and draws it as a self-contained HTML timeline:
+// verifier render [-condition c] [-relax fifo=k] [-width px] [-o file] <history.jsonl | binlog dir>
+func render(args []string) {
+	fs := flag.NewFlagSet("render", flag.ExitOnError)
+	conditionFlag := fs.String("condition", "", "correctness condition, by default the binary logs' or linearizability")
+	relaxFlag := fs.String("relax", "", "allowed rank error per semantics, e.g. fifo=2,priority=1")
+	width := fs.Float64("width", 1200, "pixels the history's time spans")
+	out := fs.String("o", "", "write the HTML to this file instead of stdout")
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 || *width <= 0 {
+		fmt.Println("usage: verifier render [-condition c] [-relax fifo=k] [-width px] [-o file] <history.jsonl | binlog dir>")
+		os.Exit(2)
+	}
+
+	path := fs.Arg(0)
+	info, err := os.Stat(path)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	var methods []Method
+	condition = LINEARIZABILITY
+	if info.IsDir() {
+		var h binlogHeader
+		h, methods, err = readBinlogDir(path)
+		condition = h.condition
+	} else {
+		var f *os.File
+		if f, err = os.Open(path); err == nil {
+			methods, err = readRecords(f)
+			f.Close()
+		}
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if *conditionFlag != "" {
+		if condition, err = parseCondition(*conditionFlag); err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+	}
+	if relaxation, err = parseRelaxation(*relaxFlag); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+
+	v, items := verifyHistoryItems(context.Background(), methods)
+	t := buildTimeline(fmt.Sprintf("%s under %v", path, condition), methods, v, items, *width)
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := renderTimeline(w, t); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
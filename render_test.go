@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRenderTimeline(t *testing.T) {
+	// b is consumed ahead of a, which it was demoted behind
+	methods := []Method{
+		onThread(0, op(PRODUCER, FIFO, "a", true, 0, 10)),
+		onThread(1, op(PRODUCER, FIFO, "b<&>", true, 20, 30)),
+		onThread(0, op(CONSUMER, FIFO, "b<&>", true, 40, 50)),
+		onThread(1, op(CONSUMER, FIFO, "a", true, 60, 70)),
+		onThread(2, op(CONSUMER, FIFO, "", false, 80, 90)),
+	}
+	v, items := verifyHistoryItems(context.Background(), methods)
+	if v.correct {
+		t.Fatal("history verified correct")
+	}
+	tl := buildTimeline("reorder", methods, v, items, 700)
+
+	if len(tl.Lanes) != 3 || len(tl.Bars) != len(methods) {
+		t.Fatalf("%d lanes, %d bars", len(tl.Lanes), len(tl.Bars))
+	}
+	for i, bar := range tl.Bars {
+		if bar.X < timelineLeft || bar.X+bar.W > timelineLeft+700+1e-9 {
+			t.Errorf("bar %d at %v+%v outside the axis", i, bar.X, bar.W)
+		}
+		if strings.Contains(bar.Class, "violating") != (methods[i].itemAddrS == "b<&>") {
+			t.Errorf("bar %d of %s has class %q, violations %v", i, methods[i].itemAddrS, bar.Class, v.violations)
+		}
+		if !strings.Contains(bar.Tip, "sumF") && methods[i].itemAddrS != "" {
+			t.Errorf("bar %d tip %q", i, bar.Tip)
+		}
+	}
+	if !strings.Contains(tl.Bars[4].Class, "failed") {
+		t.Errorf("failed consumer has class %q", tl.Bars[4].Class)
+	}
+	if len(tl.Edges) == 0 || tl.Edges[0].Key != "b<&>" {
+		t.Fatalf("demotion chains %+v", tl.Edges)
+	}
+
+	var out bytes.Buffer
+	if err := renderTimeline(&out, tl); err != nil {
+		t.Fatal(err)
+	}
+	page := out.String()
+	for _, want := range []string{"<svg", `class="chain"`, "violating", "b&lt;&amp;&gt;"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page lacks %q", want)
+		}
+	}
+	// self-contained, and the key never reaches the page unescaped
+	for _, bad := range []string{"src=", "href=", "b<&>"} {
+		if strings.Contains(page, bad) {
+			t.Errorf("page contains %q", bad)
+		}
+	}
+}
@@ -0,0 +1,446 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/servolino/verifier/verifierpb"
+	"google.golang.org/protobuf/encoding/protodelim"
+)
+
+// records verified in one go while a request is still streaming
+const serveBatch = 1000
+
+type session struct {
+	condition     Condition
+	relaxation    [PRIORITY + 1]int
+	methods       []Method
+	items         []Item
+	itStart       int
+	countIterated uint64
+	rankErrors    []int
+	lastResponse  map[int]int64 // latest response seen per thread
+	verdict       Verdict
+	closed        bool
+}
+
+// sessions of the HTTP service; the checkpoint verifier keeps its state in
+// globals, so the lock also serializes verification
+var sessions = struct {
+	sync.Mutex
+	next int
+	byID map[string]*session
+}{byID: make(map[string]*session)}
+
+func (s *session) add(m Method) {
+	m.id = len(s.methods)
+	s.methods = append(s.methods, m)
+	if findItem(s.items, m.itemAddrS) == -1 {
+		var item Item
+		item.setItem(m.itemAddrS)
+		s.items = append(s.items, item)
+	}
+	if m.response > s.lastResponse[m.process] {
+		s.lastResponse[m.process] = m.response
+	}
+}
+
+// restart forgets what was verified, keeping the methods
+func (s *session) restart() {
+	for i := range s.items {
+		var item Item
+		item.setItem(s.items[i].key)
+		s.items[i] = item
+	}
+	s.itStart, s.countIterated, s.rankErrors = 0, 0, nil
+	s.verdict = Verdict{correct: true}
+}
+
+// verify checks every method no thread can still be ordered before, which is
+// everything that responded before the slowest thread's latest response.
+// Once the session is closed it checks the rest.
+func (s *session) verify() {
+	next := 0
+	if s.countIterated != 0 {
+		next = s.itStart + 1
+	}
+	if next == len(s.methods) {
+		return
+	}
+
+	// a thread that joined late, or fell behind the watermark, can send
+	// methods that responded before ones already verified; those are
+	// applied in response order, so verification starts over
+	if next > 0 {
+		last := s.methods[next-1].response
+		for _, m := range s.methods[next:] {
+			if m.response < last {
+				s.restart()
+				next = 0
+				break
+			}
+		}
+	}
+
+	watermark := int64(math.MaxInt64)
+	if !s.closed {
+		// quiescent blocks are only known once the history is complete
+		if s.condition == QUIESCENT {
+			return
+		}
+		for _, r := range s.lastResponse {
+			if r < watermark {
+				watermark = r
+			}
+		}
+	}
+
+	sort.SliceStable(s.methods[next:], func(a, b int) bool {
+		return s.methods[next+a].response < s.methods[next+b].response
+	})
+	end := next
+	for end < len(s.methods) && s.methods[end].response < watermark {
+		end++
+	}
+	if end == next {
+		return
+	}
+
+	saved, savedK := condition, relaxation
+	condition, relaxation = s.condition, s.relaxation
+	rankErrors = s.rankErrors
+	finalOutcome = true
+	finalViolations = nil
+
+	if s.condition == QUIESCENT {
+		blocks := detectBlocks(s.methods)
+		verified, _ := verifyBlocks(context.Background(), s.methods, s.items, &s.itStart, &s.countIterated, blocks, nil)
+		s.verdict = Verdict{finalOutcome, finalViolations, rankErrors, blocks, verified, nil}
+	} else {
+		verifyCheckpoint(context.Background(), s.methods[:end], s.items, &s.itStart, &s.countIterated, watermark, false, nil)
+		s.verdict = Verdict{finalOutcome, finalViolations, rankErrors, nil, end, nil}
+	}
+
+	s.rankErrors = rankErrors
+	condition, relaxation = saved, savedK
+}
+
+type verdictResponse struct {
+	Session        string         `json:"session"`
+	Correct        bool           `json:"correct"`
+	Closed         bool           `json:"closed"`
+	Methods        int            `json:"methods"`
+	Verified       uint64         `json:"verified"`
+	Root           string         `json:"root"` // Merkle root of the history so far
+	Violations     []string       `json:"violations"`
+	RankErrors     []int          `json:"rank_errors,omitempty"`
+	Counterexample []methodRecord `json:"counterexample,omitempty"`
+}
+
+func (s *session) response(id string) verdictResponse {
+	r := verdictResponse{
+		Session:    id,
+		Correct:    s.verdict.correct,
+		Closed:     s.closed,
+		Methods:    len(s.methods),
+		Verified:   s.countIterated,
+		Root:       historyRoot(s.methods),
+		Violations: s.verdict.violations,
+		RankErrors: s.verdict.rankErrors,
+	}
+	if r.Violations == nil {
+		r.Violations = []string{}
+	}
+
+	for _, m := range s.counterexample() {
+		r.Counterexample = append(r.Counterexample, toRecord(m))
+	}
+	return r
+}
+
+// counterexample is every method on a violating item
+func (s *session) counterexample() []*Method {
+	bad := make(map[string]bool)
+	for _, key := range s.verdict.violations {
+		bad[key] = true
+	}
+	var methods []*Method
+	for i := range s.methods {
+		if bad[s.methods[i].itemAddrS] {
+			methods = append(methods, &s.methods[i])
+		}
+	}
+	return methods
+}
+
+// newSession registers an empty session; the caller holds sessions
+func newSession(c Condition, k [PRIORITY + 1]int) (string, *session) {
+	sessions.next++
+	id := strconv.Itoa(sessions.next)
+	s := &session{condition: c, relaxation: k, lastResponse: make(map[int]int64)}
+	s.verdict.correct = true
+	sessions.byID[id] = s
+	return id, s
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func openSession(w http.ResponseWriter, r *http.Request) {
+	c := LINEARIZABILITY
+	if q := r.URL.Query().Get("condition"); q != "" {
+		var err error
+		if c, err = parseCondition(q); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	k, err := parseRelaxation(r.URL.Query().Get("relax"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	sessions.Lock()
+	defer sessions.Unlock()
+
+	id, s := newSession(c, k)
+	writeJSON(w, http.StatusCreated, s.response(id))
+}
+
+// methodReader hands out the methods of a request body one at a time, and
+// io.EOF after the last
+type methodReader func() (Method, error)
+
+// jsonMethods reads JSON lines, one methodRecord each
+func jsonMethods(body io.Reader) methodReader {
+	scanner := bufio.NewScanner(body)
+	line := 0
+	return func() (Method, error) {
+		for scanner.Scan() {
+			line++
+			if len(scanner.Bytes()) == 0 {
+				continue
+			}
+
+			var rec methodRecord
+			if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+				return Method{}, fmt.Errorf("line %d: %v", line, err)
+			}
+			m, err := fromRecord(rec)
+			if err != nil {
+				return Method{}, fmt.Errorf("line %d: %v", line, err)
+			}
+			return m, nil
+		}
+		if err := scanner.Err(); err != nil {
+			return Method{}, err
+		}
+		return Method{}, io.EOF
+	}
+}
+
+// protoMethods reads verifierpb.Method messages, each behind its length as
+// a varint
+func protoMethods(body io.Reader) methodReader {
+	r := bufio.NewReader(body)
+	n := 0
+	return func() (Method, error) {
+		var p verifierpb.Method
+		if err := protodelim.UnmarshalFrom(r, &p); err != nil {
+			if err == io.EOF {
+				return Method{}, io.EOF
+			}
+			return Method{}, fmt.Errorf("message %d: %v", n+1, err)
+		}
+		n++
+		m, err := fromProto(&p)
+		if err != nil {
+			return Method{}, fmt.Errorf("message %d: %v", n, err)
+		}
+		return m, nil
+	}
+}
+
+// bodyMethods picks the reader for the request's Content-Type, JSON lines
+// when it has none
+func bodyMethods(r *http.Request) (methodReader, error) {
+	ct := r.Header.Get("Content-Type")
+	if ct == "" {
+		return jsonMethods(r.Body), nil
+	}
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return nil, err
+	}
+	switch mt {
+	case "application/x-ndjson", "application/jsonl", "application/json":
+		return jsonMethods(r.Body), nil
+	case "application/x-protobuf", "application/protobuf":
+		return protoMethods(r.Body), nil
+	}
+	return nil, fmt.Errorf("unsupported Content-Type %s, want application/x-ndjson or application/x-protobuf", mt)
+}
+
+// recordMethods takes a stream of methods, as JSON lines or length-delimited
+// protobuf messages, and verifies as it goes
+func recordMethods(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	sessions.Lock()
+	s, ok := sessions.byID[id]
+	sessions.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no session %s", id))
+		return
+	}
+
+	next, err := bodyMethods(r)
+	if err != nil {
+		writeError(w, http.StatusUnsupportedMediaType, err)
+		return
+	}
+	batch := make([]Method, 0, serveBatch)
+
+	flush := func() {
+		sessions.Lock()
+		defer sessions.Unlock()
+
+		if s.closed {
+			err = fmt.Errorf("session %s is closed", id)
+			return
+		}
+		for _, m := range batch {
+			s.add(m)
+		}
+		batch = batch[:0]
+		s.verify()
+	}
+
+	for err == nil {
+		var m Method
+		if m, err = next(); err != nil {
+			break
+		}
+		batch = append(batch, m)
+		if len(batch) == serveBatch {
+			flush()
+		}
+	}
+	if err == io.EOF {
+		err = nil
+		flush()
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	sessions.Lock()
+	defer sessions.Unlock()
+	writeJSON(w, http.StatusOK, s.response(id))
+}
+
+func getVerdict(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	sessions.Lock()
+	defer sessions.Unlock()
+
+	s, ok := sessions.byID[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no session %s", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, s.response(id))
+}
+
+func closeSession(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	sessions.Lock()
+	defer sessions.Unlock()
+
+	s, ok := sessions.byID[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no session %s", id))
+		return
+	}
+	s.closed = true
+	s.verify()
+	writeJSON(w, http.StatusOK, s.response(id))
+}
+
+func deleteSession(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	sessions.Lock()
+	defer sessions.Unlock()
+
+	delete(sessions.byID, id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func newServeMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /sessions", openSession)
+	mux.HandleFunc("POST /sessions/{id}/methods", recordMethods)
+	mux.HandleFunc("POST /sessions/{id}/close", closeSession)
+	mux.HandleFunc("GET /sessions/{id}", getVerdict)
+	mux.HandleFunc("DELETE /sessions/{id}", deleteSession)
+	return mux
+}
+
+// serve runs the verifier as an HTTP service, and a gRPC one alongside it
+// when asked: verifier serve [-addr :8080] [-grpc :9090] [-metrics]
+func serve(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "address to listen on")
+	grpcAddr := fs.String("grpc", "", "address to serve gRPC on")
+	metrics := fs.Bool("metrics", false, "serve Prometheus metrics on /metrics")
+	_ = fs.Parse(args)
+
+	if *grpcAddr != "" {
+		lis, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		fmt.Printf("Serving gRPC on %s\n", *grpcAddr)
+		go func() {
+			if err := newGRPCServer().Serve(lis); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}()
+	}
+
+	fmt.Printf("Serving verification sessions on %s\n", *addr)
+	mux := newServeMux()
+	if *metrics {
+		mux.Handle("GET /metrics", metricsHandler())
+	}
+	if err := http.ListenAndServe(*addr, mux); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/servolino/verifier/verifierpb"
+	"google.golang.org/protobuf/encoding/protodelim"
+)
+
+func postJSON(t *testing.T, url string, body string, v interface{}) int {
+	t.Helper()
+
+	resp, err := http.Post(url, "application/x-ndjson", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestServeSession(t *testing.T) {
+	srv := httptest.NewServer(newServeMux())
+	defer srv.Close()
+
+	var open verdictResponse
+	if code := postJSON(t, srv.URL+"/sessions", "", &open); code != http.StatusCreated {
+		t.Fatalf("open: status %d", code)
+	}
+	base := srv.URL + "/sessions/" + open.Session
+
+	var v verdictResponse
+	postJSON(t, base+"/methods", `{"thread":0,"type":"producer","semantics":"fifo","key":"a","invocation":0,"response":1,"status":true}
+{"thread":1,"type":"producer","semantics":"fifo","key":"b","invocation":2,"response":3,"status":true}
+`, &v)
+	if !v.Correct || v.Methods != 2 {
+		t.Fatalf("after producers: %+v", v)
+	}
+
+	// b overtakes a
+	postJSON(t, base+"/methods", `{"thread":1,"type":"consumer","semantics":"fifo","key":"b","invocation":4,"response":5,"status":true}
+{"thread":0,"type":"consumer","semantics":"fifo","key":"a","invocation":6,"response":7,"status":true}
+`, &v)
+	if v.Verified == 0 {
+		t.Errorf("nothing verified while streaming: %+v", v)
+	}
+
+	postJSON(t, base+"/close", "", &v)
+	if v.Correct || !v.Closed || v.Verified != 4 {
+		t.Fatalf("after close: %+v", v)
+	}
+	if len(v.Violations) != 1 || v.Violations[0] != "b" {
+		t.Errorf("violations %v, want [b]", v.Violations)
+	}
+	if len(v.Counterexample) != 2 {
+		t.Errorf("counterexample %+v, want the two methods on b", v.Counterexample)
+	}
+
+	if code := postJSON(t, base+"/methods", `{"type":"producer","semantics":"fifo","key":"c"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("record into closed session: status %d", code)
+	}
+}
+
+func TestServeLateThread(t *testing.T) {
+	srv := httptest.NewServer(newServeMux())
+	defer srv.Close()
+
+	var open verdictResponse
+	postJSON(t, srv.URL+"/sessions", "", &open)
+	base := srv.URL + "/sessions/" + open.Session
+
+	// thread 0 alone, so its methods are verified up to its own responses
+	var v verdictResponse
+	postJSON(t, base+"/methods", `{"thread":0,"type":"producer","semantics":"fifo","key":"a","invocation":0,"response":1,"status":true}
+{"thread":0,"type":"consumer","semantics":"fifo","key":"a","invocation":10,"response":11,"status":true}
+{"thread":0,"type":"producer","semantics":"fifo","key":"c","invocation":12,"response":13,"status":true}
+`, &v)
+	if v.Verified == 0 {
+		t.Fatalf("nothing verified before thread 1 joined: %+v", v)
+	}
+
+	// thread 1 joins with b enqueued and dequeued before a was dequeued
+	postJSON(t, base+"/methods", `{"thread":1,"type":"producer","semantics":"fifo","key":"b","invocation":2,"response":3,"status":true}
+{"thread":1,"type":"consumer","semantics":"fifo","key":"b","invocation":4,"response":5,"status":true}
+`, nil)
+	postJSON(t, base+"/close", "", &v)
+	if v.Correct || v.Verified != 5 || len(v.Violations) != 1 || v.Violations[0] != "b" {
+		t.Errorf("after close: %+v", v)
+	}
+
+	// the same history in one go
+	postJSON(t, srv.URL+"/sessions", "", &open)
+	base = srv.URL + "/sessions/" + open.Session
+	postJSON(t, base+"/methods", `{"thread":0,"type":"producer","semantics":"fifo","key":"a","invocation":0,"response":1,"status":true}
+{"thread":0,"type":"consumer","semantics":"fifo","key":"a","invocation":10,"response":11,"status":true}
+{"thread":0,"type":"producer","semantics":"fifo","key":"c","invocation":12,"response":13,"status":true}
+{"thread":1,"type":"producer","semantics":"fifo","key":"b","invocation":2,"response":3,"status":true}
+{"thread":1,"type":"consumer","semantics":"fifo","key":"b","invocation":4,"response":5,"status":true}
+`, nil)
+	var batch verdictResponse
+	postJSON(t, base+"/close", "", &batch)
+	if batch.Correct != v.Correct || len(batch.Violations) != len(v.Violations) {
+		t.Errorf("streamed %+v, in one batch %+v", v, batch)
+	}
+}
+
+func TestServeRelaxed(t *testing.T) {
+	srv := httptest.NewServer(newServeMux())
+	defer srv.Close()
+
+	var open verdictResponse
+	if code := postJSON(t, srv.URL+"/sessions?relax=fifo=1", "", &open); code != http.StatusCreated {
+		t.Fatalf("open: status %d", code)
+	}
+	base := srv.URL + "/sessions/" + open.Session
+
+	// b overtakes a, one rank error
+	var v verdictResponse
+	postJSON(t, base+"/methods", `{"thread":0,"type":"producer","semantics":"fifo","key":"a","invocation":0,"response":1,"status":true}
+{"thread":0,"type":"producer","semantics":"fifo","key":"b","invocation":2,"response":3,"status":true}
+{"thread":0,"type":"consumer","semantics":"fifo","key":"b","invocation":4,"response":5,"status":true}
+{"thread":0,"type":"consumer","semantics":"fifo","key":"a","invocation":6,"response":7,"status":true}
+`, nil)
+	postJSON(t, base+"/close", "", &v)
+	if !v.Correct || len(v.RankErrors) < 2 || v.RankErrors[1] != 1 {
+		t.Errorf("1-FIFO session: %+v", v)
+	}
+	if relaxation != [PRIORITY + 1]int{} {
+		t.Errorf("session left relaxation %v behind", relaxation)
+	}
+
+	if code := postJSON(t, srv.URL+"/sessions?relax=set=1", "", nil); code != http.StatusBadRequest {
+		t.Errorf("relaxed set: status %d", code)
+	}
+}
+
+func TestServeProtobuf(t *testing.T) {
+	srv := httptest.NewServer(newServeMux())
+	defer srv.Close()
+
+	var open verdictResponse
+	postJSON(t, srv.URL+"/sessions", "", &open)
+	base := srv.URL + "/sessions/" + open.Session
+
+	// b overtakes a
+	var body bytes.Buffer
+	for _, m := range []*verifierpb.Method{
+		pbOp(0, verifierpb.Types_PRODUCER, "a", 0, 1),
+		pbOp(1, verifierpb.Types_PRODUCER, "b", 2, 3),
+		pbOp(1, verifierpb.Types_CONSUMER, "b", 4, 5),
+		pbOp(0, verifierpb.Types_CONSUMER, "a", 6, 7),
+	} {
+		if _, err := protodelim.MarshalTo(&body, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resp, err := http.Post(base+"/methods", "application/x-protobuf", &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+
+	var v verdictResponse
+	postJSON(t, base+"/close", "", &v)
+	if v.Correct || v.Methods != 4 || len(v.Violations) != 1 || v.Violations[0] != "b" {
+		t.Errorf("after close: %+v", v)
+	}
+
+	// a message cut short
+	postJSON(t, srv.URL+"/sessions", "", &open)
+	resp, err = http.Post(srv.URL+"/sessions/"+open.Session+"/methods", "application/x-protobuf", bytes.NewReader([]byte{10, 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("truncated message: status %d", resp.StatusCode)
+	}
+
+	resp, err = http.Post(srv.URL+"/sessions/"+open.Session+"/methods", "text/csv", strings.NewReader("a,b\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("csv: status %d", resp.StatusCode)
+	}
+}
+
+func TestServeBadRecord(t *testing.T) {
+	srv := httptest.NewServer(newServeMux())
+	defer srv.Close()
+
+	var open verdictResponse
+	postJSON(t, srv.URL+"/sessions", "", &open)
+
+	code := postJSON(t, srv.URL+"/sessions/"+open.Session+"/methods", `{"type":"enqueue","semantics":"fifo","key":"a"}`, nil)
+	if code != http.StatusBadRequest {
+		t.Errorf("status %d, want %d", code, http.StatusBadRequest)
+	}
+	if code := postJSON(t, srv.URL+"/sessions/nope/close", "", nil); code != http.StatusNotFound {
+		t.Errorf("status %d, want %d", code, http.StatusNotFound)
+	}
+}
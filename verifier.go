@@ -2,14 +2,19 @@ package main
 
 import (
 	"C"
+	"context"
+	"flag"
 	"fmt"
 	"github.com/golang-collections/collections/queue"
 	"github.com/golang-collections/collections/stack"
 	"go.uber.org/atomic"
+	"log/slog"
 	"math"
-	"math/rand"
 	"os"
+	"os/signal"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	Atomic "sync/atomic"
 	"syscall"
@@ -41,6 +46,39 @@ const (
 	PRIORITY
 )
 
+type Condition int
+
+const (
+	LINEARIZABILITY Condition = iota
+	SEQUENTIAL                // only per-thread order is kept
+	QUIESCENT                 // only order across quiescent points is kept
+)
+
+// correctness condition the checkpoints are verified against
+var condition Condition
+
+func parseCondition(s string) (Condition, error) {
+	switch s {
+	case "linearizability", "linearizable":
+		return LINEARIZABILITY, nil
+	case "sequential", "sc":
+		return SEQUENTIAL, nil
+	case "quiescent", "qc":
+		return QUIESCENT, nil
+	}
+	return LINEARIZABILITY, fmt.Errorf("unknown correctness condition %q", s)
+}
+
+func (c Condition) String() string {
+	switch c {
+	case SEQUENTIAL:
+		return "sequential"
+	case QUIESCENT:
+		return "quiescent"
+	}
+	return "linearizability"
+}
+
 type Types int
 
 const (
@@ -51,29 +89,36 @@ const (
 )
 
 type Method struct {
-	id          int       // atomic var
-	itemAddrS    string       // sender account address
-	itemAddrR    string   // receiver account address
-	itemBalance int       // account balance
-	semantics   Semantics // hardcode as FIFO per last email
-	types       Types     // producing/consuming  adding/subtracting
-	status      bool
-	senderID    int       // same as itemAddr ??
-	requestAmnt int
-	txnCtr      int32
+	id              int       // atomic var
+	itemAddrS       string    // sender account address
+	itemAddrR       string    // receiver account address
+	itemBalance     int       // account balance, lowest is served first under PRIORITY
+	semantics       Semantics // hardcode as FIFO per last email
+	types           Types     // producing/consuming  adding/subtracting
+	status          bool
+	senderID        int // same as itemAddr ??
+	requestAmnt     int
+	txnCtr          int32
+	invocation      int64 // nanoseconds since start
+	response        int64 // nanoseconds since start
+	process         int   // thread that called the method
+	quiescentPeriod int   // index of the block the method falls in
+	nonce           int64 // sender's sequence number, transactions only
 }
 
 type TransactionData struct {
-	addrSender   string
-	addrReceiver string
-	balanceSender int
+	addrSender      string
+	addrReceiver    string
+	balanceSender   int
 	balanceReceiver int
-	amount       int
-	tId          int32
+	amount          int
+	tId             int32
+	nonce           int64
+	types           Types // PRODUCER for a transfer, READER or WRITER of the sender's memo
 }
 
 type AtomicTxnCtr struct {
-	val int64
+	val  int64
 	lock sync.Mutex
 }
 
@@ -126,13 +171,15 @@ func WriteToFile(filename string, data string) error {
 
 	defer f.Close()
 	_, err = fmt.Fprintf(f, "%s", data)
-	return  err
+	return err
 }
 
-func processTimer(start time.Time, txCount *int32) {
+// processTimer appends the transactions run per second to results.txt,
+// txCount is read when the timer stops
+func processTimer(start time.Time, txCount *int64) {
 	nanoseconds := time.Since(start).Nanoseconds()
 	seconds := float64(nanoseconds) / 1e9
-	throughput := float64(*txCount) / seconds
+	throughput := float64(Atomic.LoadInt64(txCount)) / seconds
 
 	s := fmt.Sprintf("%d\t%f\n", numThreads, throughput)
 	_ = WriteToFile("results.txt", s)
@@ -154,7 +201,7 @@ func (m *Method) setMethod(id int, itemAddrS string, itemAddrR string, itemBalan
 
 type Item struct {
 	key           string // Account Hash ???
-	value         int // Account Balance ???
+	value         int    // Account Balance ???
 	sum           float64
 	numerator     int64
 	denominator   int64
@@ -162,7 +209,10 @@ type Item struct {
 	status        Status
 	promoteItems  stack.Stack
 	demoteMethods []*Method
-	producer      int // map iterator
+	producer      int       // map iterator
+	consumer      int       // map iterator
+	failedMethods []*Method // failed consumers that saw this item present
+	readMethods   []*Method // reads that found this item missing
 
 	// Failed Consumer
 	sumF         float64
@@ -221,7 +271,7 @@ func (i *Item) addInt(x int64) {
 	i.numerator = i.numerator + addNum
 
 	// C.printf("addNum = %ld, numerator/denominator = %ld\n", add_num, numerator/denominator);
-	i.sum = float64(i.numerator / i.denominator)
+	i.sum = float64(i.numerator) / float64(i.denominator)
 
 	// i.sum = i.sum + x
 }
@@ -234,21 +284,16 @@ func (i *Item) subInt(x int64) {
 	i.numerator = i.numerator - subNum
 
 	// C.printf("subNum = %ld, i.numerator/i.denominator = %ld\n", subNum, i.numerator/i.denominator);
-	i.sum = float64(i.numerator / i.denominator)
+	i.sum = float64(i.numerator) / float64(i.denominator)
 
 	// i.sum = i.sum + x
 }
 
 func (i *Item) addFrac(num int64, den int64) {
 
-	// #if DEBUG_
-	// if den == 0 {
-	// 	 C.printf("WARNING: add_frac: den = 0\n")
-	// }
-	// if i.denominator == 0 {
-	//	 C.printf("WARNING: add_frac: 1. denominator = 0\n")
-	// }
-	// #endif
+	if logging(checkpointLog, LevelTrace) && (den == 0 || i.denominator == 0) {
+		logAt(checkpointLog, LevelTrace, "addFrac: zero denominator", "item", i.key, "den", den, "denominator", i.denominator)
+	}
 
 	if i.denominator%den == 0 {
 		i.numerator = i.numerator + num*i.denominator/den
@@ -260,23 +305,18 @@ func (i *Item) addFrac(num int64, den int64) {
 		i.denominator = i.denominator * den
 	}
 
-	// #if DEBUG_
-	// if i.denominator == 0 {
-	//   C.printf("WARNING: addFrac: 2. denominator = 0\n")
-	// }
-	// #endif
+	if logging(checkpointLog, LevelTrace) && i.denominator == 0 {
+		logAt(checkpointLog, LevelTrace, "addFrac: zero denominator after adding", "item", i.key)
+	}
 
-	i.sum = float64(i.numerator / i.denominator)
+	i.sum = float64(i.numerator) / float64(i.denominator)
 }
 
 func (i *Item) subFrac(num, den int64) {
 
-	// #if DEBUG_
-	// if den == 0
-	// 	 C.printf("WARNING: subFrac: den = 0\n")
-	// if i.denominator == 0
-	//	 C.printf("WARNING: subFrac: 1. denominator = 0\n");
-	// #endif
+	if logging(checkpointLog, LevelTrace) && (den == 0 || i.denominator == 0) {
+		logAt(checkpointLog, LevelTrace, "subFrac: zero denominator", "item", i.key, "den", den, "denominator", i.denominator)
+	}
 
 	if i.denominator%den == 0 {
 		i.numerator = i.numerator - num*i.denominator/den
@@ -288,12 +328,11 @@ func (i *Item) subFrac(num, den int64) {
 		i.denominator = i.denominator * den
 	}
 
-	// #if DEBUG_
-	// if denominator == 0
-	//	 C.printf("WARNING: subFrac: 2. denominator = 0\n")
-	// #endif
+	if logging(checkpointLog, LevelTrace) && i.denominator == 0 {
+		logAt(checkpointLog, LevelTrace, "subFrac: zero denominator after subtracting", "item", i.key)
+	}
 
-	i.sum = float64(i.numerator / i.denominator)
+	i.sum = float64(i.numerator) / float64(i.denominator)
 }
 
 func (i *Item) demote() {
@@ -307,10 +346,9 @@ func (i *Item) demote() {
 func (i *Item) promote() {
 	den := int64(math.Exp2(i.exponent))
 
-	// #if DEBUG_
-	// if den == 0
-	//   C.printf("2 ^ %f = %ld?\n", i.exponent, den);
-	// #endif
+	if logging(checkpointLog, LevelTrace) && den == 0 {
+		logAt(checkpointLog, LevelTrace, "promote: zero denominator", "item", i.key, "exponent", i.exponent)
+	}
 
 	if i.exponent < 0 {
 		den = 1
@@ -324,12 +362,9 @@ func (i *Item) promote() {
 
 func (i *Item) addFracFailed(num int64, den int64) {
 
-	// #if DEBUG_
-	// if den == 0
-	//   C.printf("WARNING: addFracFailed: den = 0\n");
-	// if denominatorF == 0
-	//   C.printf("WARNING: addFracFailed: 1. denominatorF = 0\n")
-	// #endif
+	if logging(checkpointLog, LevelTrace) && (den == 0 || i.denominatorF == 0) {
+		logAt(checkpointLog, LevelTrace, "addFracFailed: zero denominator", "item", i.key, "den", den, "denominatorF", i.denominatorF)
+	}
 
 	if i.denominatorF%den == 0 {
 		i.numeratorF = i.numeratorF + num*i.denominatorF/den
@@ -341,21 +376,17 @@ func (i *Item) addFracFailed(num int64, den int64) {
 		i.denominatorF = i.denominatorF * den
 	}
 
-	// #if DEBUG_
-	// if denominatorF == 0
-	//   C.printf("WARNING: addFracFailed: 2. denominatorF = 0\n");
-	// #endif
+	if logging(checkpointLog, LevelTrace) && i.denominatorF == 0 {
+		logAt(checkpointLog, LevelTrace, "addFracFailed: zero denominator after adding", "item", i.key)
+	}
 
 	i.sumF = float64(i.numeratorF) / float64(i.denominatorF)
 }
 
 func (i *Item) subFracFailed(num int64, den int64) {
-	// #if DEBUG_
-	// if(den == 0)
-	// C.printf("WARNING: sub_frac_f: den = 0\n");
-	// if(denominator_f == 0)
-	// C.printf("WARNING: sub_frac_f: 1. denominator_f = 0\n");
-	// #endif
+	if logging(checkpointLog, LevelTrace) && (den == 0 || i.denominatorF == 0) {
+		logAt(checkpointLog, LevelTrace, "subFracFailed: zero denominator", "item", i.key, "den", den, "denominatorF", i.denominatorF)
+	}
 	if i.denominatorF%den == 0 {
 		i.numeratorF = i.numeratorF - num*i.denominatorF/den
 	} else if den%i.denominatorF == 0 {
@@ -365,10 +396,9 @@ func (i *Item) subFracFailed(num int64, den int64) {
 		i.numeratorF = i.numeratorF*den - num*i.denominatorF
 		i.denominatorF = i.denominatorF * den
 	}
-	// #if DEBUG_
-	// if(denominator_f == 0)
-	// C.printf("WARNING: sub_frac_f: 2. denominator_f = 0\n");
-	// #endif
+	if logging(checkpointLog, LevelTrace) && i.denominatorF == 0 {
+		logAt(checkpointLog, LevelTrace, "subFracFailed: zero denominator after subtracting", "item", i.key)
+	}
 	i.sumF = float64(i.numeratorF) / float64(i.denominatorF)
 }
 
@@ -381,12 +411,15 @@ func (i *Item) demoteFailed() {
 
 func (i *Item) promoteFailed() {
 	var den = int64(math.Exp2(i.exponentF))
+	if i.exponentF < 0 {
+		den = 1
+	}
 	// C.printf("denominator = %ld\n", den);
 	i.addFracFailed(1, den)
 	i.exponentF = i.exponentF - 1
 }
 
-//Reader
+// Reader
 func (i *Item) addFracReader(num int64, den int64) {
 	if i.denominatorR%den == 0 {
 		i.numeratorR = i.numeratorR + num*i.denominatorR/den
@@ -398,21 +431,21 @@ func (i *Item) addFracReader(num int64, den int64) {
 		i.denominatorR = i.denominatorR * den
 	}
 
-	i.sumR = float64(i.numeratorR / i.denominatorR)
+	i.sumR = float64(i.numeratorR) / float64(i.denominatorR)
 }
 
 func (i *Item) subFracReader(num int64, den int64) {
 	if i.denominatorR%den == 0 {
 		i.numeratorR = i.numeratorR - num*i.denominatorR/den
 	} else if den%i.denominatorR == 0 {
-		i.denominatorR = i.numeratorR*den/i.denominatorR - num
+		i.numeratorR = i.numeratorR*den/i.denominatorR - num
 		i.denominatorR = den
 	} else {
 		i.numeratorR = i.numeratorR*den - num*i.denominatorR
 		i.denominatorR = i.denominatorR * den
 	}
 
-	i.sumR = float64(i.numeratorR / i.denominatorR)
+	i.sumR = float64(i.numeratorR) / float64(i.denominatorR)
 }
 
 func (i *Item) demoteReader() {
@@ -424,6 +457,9 @@ func (i *Item) demoteReader() {
 
 func (i *Item) promoteReader() {
 	var den = int64(math.Exp2(i.exponentR))
+	if i.exponentR < 0 {
+		den = 1
+	}
 	// C.printf("denominator = %ld\n", den);
 	i.addFracReader(1, den)
 	i.exponentR = i.exponentR - 1
@@ -434,6 +470,17 @@ func (i *Item) promoteReader() {
 type Block struct {
 	start  int64
 	finish int64
+
+	// filled in when the block is verified
+	methods    int
+	correct    bool
+	violations []string // items that first failed in this block
+
+	// ledger blocks: the transactions committed together at finish, the
+	// balances after them and the root of those
+	txns     []TransactionData
+	balances map[string]int
+	root     string
 }
 
 func (b *Block) setBlock() {
@@ -441,9 +488,113 @@ func (b *Block) setBlock() {
 	b.finish = 0
 }
 
+// detectBlocks splits the history at its quiescent points, where no method is
+// pending, and stamps every method with the index of its block
+func detectBlocks(methods []Method) []Block {
+	return extendBlocks(methods, 0, make([]Block, 0))
+}
+
+// extendBlocks stamps methods[from:] with their blocks, opening new blocks
+// after the last of blocks and leaving the earlier ones as they are. A method
+// invoked before the last block finished joins it, even if it started in the
+// time of an earlier block.
+func extendBlocks(methods []Method, from int, blocks []Block) []Block {
+	order := make([]int, len(methods)-from)
+	for i := range order {
+		order[i] = from + i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return methods[order[a]].invocation < methods[order[b]].invocation
+	})
+
+	for _, i := range order {
+		if len(blocks) == 0 || methods[i].invocation > blocks[len(blocks)-1].finish {
+			var b Block
+			b.setBlock()
+			b.start = methods[i].invocation
+			b.finish = methods[i].response
+			blocks = append(blocks, b)
+		} else if methods[i].response > blocks[len(blocks)-1].finish {
+			blocks[len(blocks)-1].finish = methods[i].response
+		}
+		methods[i].quiescentPeriod = len(blocks) - 1
+	}
+	return blocks
+}
+
 // End of Block struct
 
 var finalOutcome bool
+var finalViolations []string // keys of the items that failed the last checkpoint
+
+// allowed rank error per semantics, 0 is strict (k-FIFO, k-LIFO, k-priority)
+var relaxation [PRIORITY + 1]int
+
+// parseRelaxation reads allowed rank errors like fifo=2,priority=1, the
+// semantics left out staying strict
+func parseRelaxation(spec string) ([PRIORITY + 1]int, error) {
+	var r [PRIORITY + 1]int
+	if spec == "" {
+		return r, nil
+	}
+	for _, part := range strings.Split(spec, ",") {
+		name, k, ok := strings.Cut(strings.TrimSpace(part), "=")
+		n, err := strconv.Atoi(k)
+		if !ok || err != nil || n < 0 {
+			return r, fmt.Errorf("relaxation %q: want semantics=k", part)
+		}
+		s, err := parseSemantics(name)
+		if err != nil {
+			return r, err
+		}
+		if s != FIFO && s != LIFO && s != PRIORITY {
+			return r, fmt.Errorf("relaxation %q: only fifo, lifo and priority relax", part)
+		}
+		r[s] = n
+	}
+	return r, nil
+}
+
+// relaxationString is r the way parseRelaxation reads it, empty when strict
+func relaxationString(r [PRIORITY + 1]int) string {
+	var parts []string
+	for s, k := range r {
+		if k != 0 {
+			parts = append(parts, fmt.Sprintf("%v=%d", Semantics(s), k))
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// rankErrors[r] counts the consumers that skipped r items that were ahead of theirs
+var rankErrors []int
+
+// addViolation fails the run on an item, listing it once
+func addViolation(key string) {
+	finalOutcome = false
+	for _, k := range finalViolations {
+		if k == key {
+			return
+		}
+	}
+	finalViolations = append(finalViolations, key)
+}
+
+func recordRankError(semantics Semantics, rank int) {
+	if semantics != FIFO && semantics != LIFO && semantics != PRIORITY {
+		return
+	}
+	for len(rankErrors) <= rank {
+		rankErrors = append(rankErrors, 0)
+	}
+	rankErrors[rank]++
+}
+
+// maxRankError is the largest rank error counted in errs, -1 for none
+func maxRankError(errs []int) int {
+	return len(errs) - 1
+}
+
 var methodCount int32
 
 func fncomp(lhs, rhs int64) bool {
@@ -453,23 +604,86 @@ func fncomp(lhs, rhs int64) bool {
 var q queue.Queue
 var s stack.Stack
 
-var threadLists ConcurrentSlice // empty slice with capacity numThreads
-var threadListsSize= make([]atomic.Int32, numThreads, numThreads) // atomic ops only
-var done = make([]atomic.Bool, 32, numThreads)             // atomic ops only
-var barrier int32                                         // atomic int
+var threadLists ConcurrentSlice                                    // empty slice with capacity numThreads
+var threadListsSize = make([]atomic.Int32, numThreads, numThreads) // atomic ops only
+
+// collected is signalled under threadLists' lock when a thread records
+// methods, finishes or arrives at the barrier
+var collected = sync.NewCond(&threadLists)
+var running int // threads still working, guarded by threadLists
+var arrived int // threads at the barrier, guarded by threadLists
+
+// broadcastOnDone wakes everything waiting on collected once ctx is done
+func broadcastOnDone(ctx context.Context) (stop func() bool) {
+	return context.AfterFunc(ctx, func() {
+		threadLists.Lock()
+		collected.Broadcast()
+		threadLists.Unlock()
+	})
+}
+
+// wait blocks until every running thread has arrived, or ctx is done
+func wait(ctx context.Context) error {
+	defer broadcastOnDone(ctx)()
+	threadLists.Lock()
+	defer threadLists.Unlock()
+	arrived++
+	collected.Broadcast()
+	for arrived < running {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		collected.Wait()
+	}
+	return nil
+}
+
+// record appends methods a thread called to its list and wakes the collector
+func record(id int, ms ...Method) {
+	threadLists.Lock()
+	threadLists.items[id] = append(threadLists.items[id].([]Method), ms...)
+	threadListsSize[id].Add(int32(len(ms)))
+	collected.Broadcast()
+	threadLists.Unlock()
+}
+
+// finishThread tells the collector a thread has recorded all its methods
+func finishThread() {
+	threadLists.Lock()
+	running--
+	collected.Broadcast()
+	threadLists.Unlock()
+}
 
-func wait() {
-	Atomic.AddInt32(&barrier, 1)
-	for Atomic.LoadInt32(&barrier) < numThreads {
+// pending reports whether a thread recorded methods past itCount, with
+// threadLists locked
+func pending(itCount []int32) bool {
+	for i := range itCount {
+		if itCount[i] < threadListsSize[i].Load() {
+			return true
+		}
 	}
+	return false
 }
 
-var methodTime [numThreads]int64
-var overheadTime [numThreads]int64
+var methodTime [numThreads]int64   // nanoseconds each thread spent inside operations
+var overheadTime [numThreads]int64 // nanoseconds each thread spent recording them
 
 var start time.Time
 
-var elapsedTimeVerify int64
+var elapsedTimeVerify int64 // nanoseconds verify ran for
+
+// maxThreadTime is the longest time any thread spent, in seconds, since the
+// threads run side by side
+func maxThreadTime(times []int64) float64 {
+	var longest int64
+	for _, t := range times {
+		if t > longest {
+			longest = t
+		}
+	}
+	return float64(longest) * 1e-9
+}
 
 func minOf(vars []int) int {
 	if len(vars) == 0 {
@@ -484,7 +698,7 @@ func maxOf(vars []int) int {
 		return -1
 	}
 	sort.Ints(vars)
-	return vars[len(vars) - 1]
+	return vars[len(vars)-1]
 }
 
 /*func findIndexForMethod(methods []*Method, method Method, field string) int {
@@ -504,253 +718,284 @@ func maxOf(vars []int) int {
 //}
 //
 
+// precedes reports whether a has to be ordered before b under the selected condition
+func precedes(a *Method, b *Method) bool {
+	switch condition {
+	case SEQUENTIAL:
+		return a.process == b.process && a.response < b.invocation
+	case QUIESCENT:
+		return a.quiescentPeriod < b.quiescentPeriod
+	}
+	return a.response < b.invocation
+}
+
+// findItem returns the index of the item with the given key, or -1
+func findItem(items []Item, key string) int {
+	for i := range items {
+		if items[i].key == key {
+			return i
+		}
+	}
+	return -1
+}
 
 // methodMapKey and itemMapKey are meant to serve in place of iterators
-func handleFailedConsumer(methods []Method, items []Item, mk int, it int, stackFailed *stack.Stack) {
-	fmt.Printf("Handling failed consumer...it is %d\n", it)
-	fmt.Println(methods)
-	begin := 0
-	for it0 := begin; it0 != it + 1; it0++ {
-		fmt.Printf("it0 address = %s and it address = %s\nit0 requestAmnt = %d and it requestAmnt = %d\n", methods[it0].itemAddrS, methods[it].itemAddrS, methods[it0].requestAmnt, methods[it].requestAmnt)
-		// serializability
-		//todo: > or <
-		if (methods[it0].itemAddrS == methods[it].itemAddrS &&
-			math.Abs(float64(methods[it0].requestAmnt)) < math.Abs(float64(methods[it].requestAmnt)) &&
-			methods[it0].id < methods[it].id) {
-
-			fmt.Printf("Handling failed consumer 2\n")
-
-			itemItr0 := methods[it0].itemAddrS
-
-			//if methods[it0].types == PRODUCER &&
-			if methods[it0].types == CONSUMER &&
-				items[it].status == PRESENT &&
-				methods[it0].semantics == FIFO ||
-				methods[it0].semantics == LIFO ||
-				methods[it].itemAddrS == methods[it0].itemAddrS {
-				fmt.Printf("Handling failed consumer 3\n")
-				stackFailed.Push(itemItr0)
+func handleFailedConsumer(methods []Method, items []Item, it int, stackFailed *stack.Stack) {
+	if logging(failedConsumerLog, slog.LevelDebug) {
+		logAt(failedConsumerLog, slog.LevelDebug, "handling failed consumer", "method", it, "key", methods[it].itemAddrS)
+	}
+	// a failed queue or stack consumer means the structure was empty, a failed
+	// read or set/map removal only that its own key was missing
+	sameKey := methods[it].types == READER || methods[it].semantics == SET || methods[it].semantics == MAPP
+	for it0 := 0; it0 != it; it0++ {
+		// an item produced before the failed consumer was invoked and still
+		// present means the structure was not empty
+		if !precedes(&methods[it0], &methods[it]) ||
+			(methods[it0].types != PRODUCER && methods[it0].types != WRITER) {
+			continue
+		}
+
+		itItems0 := findItem(items, methods[it0].itemAddrS)
+		if itItems0 == -1 {
+			continue
+		}
+
+		if sameKey && methods[it0].itemAddrS != methods[it].itemAddrS {
+			continue
+		}
+
+		if items[itItems0].status == PRESENT &&
+			items[itItems0].producer == it0 &&
+			items[itItems0].sum > 0 {
+			if logging(failedConsumerLog, LevelTrace) {
+				logAt(failedConsumerLog, LevelTrace, "item present before failed consumer", "method", it, "item", items[itItems0].key)
 			}
-		} else if (methods[it0].itemAddrS == methods[it].itemAddrS &&
-					math.Abs(float64(methods[it0].requestAmnt)) > math.Abs(float64(methods[it].requestAmnt)) &&
-					methods[it0].id > methods[it].id) {
+			stackFailed.Push(itItems0)
+		}
+	}
+}
 
-			fmt.Printf("Handling failed consumer 4\n")
+// failConsumer demotes every item a failed consumer (or reader) should have seen
+func failConsumer(methods []Method, items []Item, it int, stackFailed *stack.Stack) {
+	handleFailedConsumer(methods, items, it, stackFailed)
+	for stackFailed.Len() != 0 {
+		itTop := stackFailed.Pop().(int)
+		if n := len(items[itTop].failedMethods); n != 0 && items[itTop].failedMethods[n-1] == &methods[it] {
+			continue
+		}
+		items[itTop].demoteFailed()
+		items[itTop].failedMethods = append(items[itTop].failedMethods, &methods[it])
+	}
+}
 
-			itemItr0 := methods[it0].itemAddrS
+// promoteReads undoes the demotion of reads that overlap the method producing the item
+func promoteReads(methods []Method, items []Item, itItems int, it int) {
+	reads := items[itItems].readMethods[:0]
+	for _, mr := range items[itItems].readMethods {
+		if precedes(mr, &methods[it]) {
+			reads = append(reads, mr)
+			continue
+		}
+		items[itItems].promoteReader()
+	}
+	items[itItems].readMethods = reads
+}
 
-			if methods[it0].types == CONSUMER &&
-				items[it].status == PRESENT &&
-				methods[it0].semantics == FIFO ||
-				methods[it0].semantics == LIFO ||
-				methods[it].itemAddrS == methods[it0].itemAddrS {
-				fmt.Printf("Handling failed consumer 5\n")
-				stackFailed.Push(itemItr0)
+// promoteItems undoes the demotions an item placed on the items produced after it
+func promoteItems(methods []Method, items []Item, itItems int, it int) {
+	for items[itItems].promoteItems.Len() != 0 {
+		itPromoteItem := items[itItems].promoteItems.Pop().(int)
+
+		// an item that was already consumed before this consumer was invoked
+		// was taken out of order, so it keeps its demotion
+		if items[itPromoteItem].status == ABSENT &&
+			precedes(&methods[items[itPromoteItem].consumer], &methods[it]) {
+			continue
+		}
+		items[itPromoteItem].promote()
+	}
+}
+
+// dropPromotions takes the item at itItems off the promotions the items
+// ahead of it still owe it, once relaxation has absorbed its demotions, so
+// they cannot promote it a second time
+func dropPromotions(items []Item, itItems int) {
+	for _, m := range items[itItems].demoteMethods {
+		ahead := findItem(items, m.itemAddrS)
+		if ahead == -1 {
+			continue
+		}
+
+		var kept []int
+		dropped := false
+		for items[ahead].promoteItems.Len() != 0 {
+			itPromoteItem := items[ahead].promoteItems.Pop().(int)
+			if itPromoteItem == itItems && !dropped {
+				dropped = true
+				continue
 			}
+			kept = append(kept, itPromoteItem)
+		}
+		for k := len(kept) - 1; k >= 0; k-- {
+			items[ahead].promoteItems.Push(kept[k])
 		}
 	}
 }
 
-func verifyCheckpoint(methods []Method, items []Item, itStart *int, countIterated *uint64, min int64, resetItStart bool, mapBlocks []Block) {
+// verifyCheckpoint applies the methods not verified yet and checks the sums.
+// It gives up with ctx's error once ctx is done, leaving the verdict of the
+// last checkpoint it finished.
+func verifyCheckpoint(ctx context.Context, methods []Method, items []Item, itStart *int, countIterated *uint64, min int64, resetItStart bool, mapBlocks []Block) error {
 	//fmt.Println("Verifying Checkpoint...")
 
-	var stackConsumer = stack.New()      // stack of map[int64]*Item
-	var stackFinishedMethods stack.Stack // stack of map[int64]*Method
-	var stackFailed stack.Stack          // stack of map[int64]*Item
+	var stackFailed stack.Stack // stack of indexes into items
 
 	methodCount = int32(len(methods))
-	//for i := range methods.Iter() {
-		//fmt.Printf("methods.items[0].semantics = %v\n", methods.items[0].(Method).semantics)
-	//}
 	if methodCount != 0 {
+		began := time.Now()
 
 		it := 0
 		end := len(methods) - 1
 
-		fmt.Printf("end of methods = %d\n", end)
+		if logging(checkpointLog, LevelTrace) {
+			logAt(checkpointLog, LevelTrace, "checkpoint", "end", end, "itStart", *itStart, "countIterated", *countIterated)
+		}
 
 		//TODO: corner case
 		if *countIterated == 0 {
-			fmt.Printf("setting resetItStart to false\n")
 			resetItStart = false
 		} else if it != end {
-			fmt.Printf("Incrementing itStart and it\n")
 			*itStart = *itStart + 1
 			it = *itStart
 		}
-		//fmt.Printf("it = %d\n", it)
 
-		for ; it != len(methods); it++ {
-			fmt.Printf("it is %d and len items is %d len methods is %d\n", it, len(items), len(methods))
-			//fmt.Printf("!The sum at %s is %f\n", items[it].key, items[it].sum)
-			/*if methods[it].response > min{
-				break
-			}
-			*/
+		// methods are applied in response order, like map_methods in the C++ verifier
+		if it < len(methods) {
+			sort.SliceStable(methods[it:], func(a, b int) bool {
+				return methods[it+a].response < methods[it+b].response
+			})
+		}
 
-			if methodCount%5000 == 0 {
-				fmt.Printf("methodCount = %d\n", methodCount)
+		for ; it < len(methods); it++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if methodCount%5000 == 0 && logging(checkpointLog, slog.LevelDebug) {
+				logAt(checkpointLog, slog.LevelDebug, "methods verified", "methodCount", methodCount)
 			}
 			methodCount = methodCount + 1
 
 			*itStart = it
 			resetItStart = false
 			*countIterated++
+			methodsVerified.Inc()
 
+			itItems := findItem(items, methods[it].itemAddrS)
+			if itItems == -1 {
+				logAt(checkpointLog, slog.LevelWarn, "no item for method", "key", methods[it].itemAddrS)
+				continue
+			}
 
-			itItems := it //methods[it].itemAddr
-			//itItems := int(methods[it].txnCtr)
-
-			// #if DEBUG_
-			/// if mapItems[itItems].status != PRESENT{
-			//  	fmt.Println("WARNING: Current item not present!")
-			//} }
+			if methods[it].types == PRODUCER {
+				items[itItems].producer = it
 
-			// if mapMethods[it].types == PRODUCER{
-			// fmt.Printf("PRODUCER invocation %ld, response %ld, item %d\n", mapMethods[it].invocation, mapMethods[it].response, mapMethods[it].itemKey)
-			// }
+				// consumed ahead of its producer, only legal if the two overlap
+				pending := items[itItems].status == ABSENT && items[itItems].sum < 0
+				if pending && precedes(&methods[items[itItems].consumer], &methods[it]) {
+					logAt(checkpointLog, slog.LevelWarn, "item consumed before it was produced", "key", items[itItems].key)
+					continue
+				}
 
-			// else if mapMethods[it].types == CONSUMER {
-			// fmt.Printf("CONSUMER invocation %ld, response %ld, item %d\n", mapMethods[it].invocation, mapMethods[it].response, mapMethods[it].itemKey)
-			// }
-			// #endif
+				if items[itItems].status == ABSENT && !pending {
 
+					// reset item parameters
+					items[itItems].status = PRESENT
+					items[itItems].demoteMethods = nil
+					items[itItems].failedMethods = nil
+				}
 
-			if methods[it].types == PRODUCER {
-				fmt.Printf("PRODUCER\n")
-				items[it].producer = it
+				items[itItems].addInt(1)
+				promoteReads(methods, items, itItems, it)
 
-				if items[itItems].status == ABSENT {
+				if methods[it].semantics == FIFO || methods[it].semantics == LIFO || methods[it].semantics == PRIORITY {
+					for it0 := 0; it0 != it; it0++ {
+						if !precedes(&methods[it0], &methods[it]) {
+							continue
+						}
+						itItems0 := findItem(items, methods[it0].itemAddrS)
 
-					// reset item parameters
-					items[it].status = PRESENT
-					items[it].demoteMethods = nil
-				}
+						if itItems0 == itItems || itItems0 == -1 ||
+							methods[it0].types != PRODUCER || methods[it0].semantics != methods[it].semantics ||
+							items[itItems0].status != PRESENT || items[itItems0].producer != it0 {
+							continue
+						}
 
-				items[it].addInt(1)
-
-				if methods[it].semantics == FIFO {
-					fmt.Println("MADE IT")
-					for it0 := 0; it0 != it + 1; it0++ {
-						fmt.Println("MADE IT in 1")
-						// serializability
-						if methods[it0].itemAddrS == methods[it].itemAddrS &&
-							methods[it0].requestAmnt < methods[it].requestAmnt {
-							fmt.Println("MADE IT in 2")
-							// #endif
-							itItems0 := 0
-
-							// Demotion
-							// FIFO Semantics
-							//if (methods.items[it0].(Method).types == PRODUCER && items.items[int(itItems0)].(Item).status == PRESENT) &&
-							if (methods[it0].types == PRODUCER && items[itItems0].status == PRESENT) &&
-								(methods[it].types == PRODUCER && methods[it0].semantics == FIFO) {
-
-
-								//items.items[itItems].(*Item).promoteItems.Push(items.items[itItems].(*Item).key)
-								//items.items[itItems].(*Item).demote()
-								//items.items[itItems].(*Item).demoteMethods = append(items.items[itItems].(*Item).demoteMethods, methods.items[it0].(*Method))
-								items[itItems0].promoteItems.Push(items[itItems].key)
-								items[itItems].demote()
-								items[itItems].demoteMethods = append(items[itItems].demoteMethods, &methods[it0])
-							}
+						// Demotion
+						// the item that has to be consumed second waits for the other one
+						ahead, itAhead, behind := itItems0, it0, itItems
+						if methods[it].semantics == LIFO ||
+							(methods[it].semantics == PRIORITY && methods[it].itemBalance < methods[it0].itemBalance) {
+							ahead, itAhead, behind = itItems, it, itItems0
 						}
+
+						items[ahead].promoteItems.Push(behind)
+						items[behind].demote()
+						items[behind].demoteMethods = append(items[behind].demoteMethods, &methods[itAhead])
 					}
 				}
 			}
 
-			/*if methods[it].semantics == FIFO {
-				for it0 := 0; it0 != it; it0++{
-					// serializability
-					if methods[it0].itemAddr == methods[it].itemAddr &&
-						methods[it0].requestAmnt > methods[it].requestAmnt{
-						// #endif
-						itItems0 := it0 //methods[it0].itemAddr
+			if methods[it].types == WRITER {
+				items[itItems].producer = it
 
-						// Demotion
-						// FIFO Semantics
-						//if (methods.items[it0].(*Method).types == PRODUCER && items.items[itItems0].(*Item).status == PRESENT) &&
-						if (methods[it0].types == PRODUCER && items[itItems0].status == PRESENT) &&
-							(methods[it].types == PRODUCER && methods[it0].semantics == FIFO) {
-
-							//items.items[itItems0].(*Item).promoteItems.Push(items.items[itItems].(*Item).key)
-							//items.items[itItems].(*Item).demote()
-							//items.items[itItems].(*Item).demoteMethods = append(items.items[itItems].(*Item).demoteMethods, methods.items[it0].(*Method))
-						}
-					}
+				// a write overwrites, so the item is present at most once
+				if items[itItems].status == ABSENT || items[itItems].sum <= 0 {
+					items[itItems].status = PRESENT
+					items[itItems].addInt(1)
 				}
-			}*/
-			//tempMethods = methods.items[it].(Method)
-			//if methods.items[it].(*Method).types == CONSUMER {
-			if methods[it].types == CONSUMER {
-				fmt.Printf("CONSUMER\n")
-				/*std::unordered_map<int,std::unordered_map<int,Item>::iterator>::iterator it_consumer;
-				it_consumer = map_consumer.find((it->second).key);
-				if(it_consumer == map_consumer.end())
-				{
-					std::pair<int,std::unordered_map<int,Item>::iterator> entry ((it->second).key,it);
-					//map_consumer.insert(std::make_pair<int,std::unordered_map<int,Item>::iterator>((it->second).key,it));
-					map_consumer.insert(entry);
-				} else {
-					it_consumer->second = it_item_0;
-				}*/
+				promoteReads(methods, items, itItems, it)
+			}
 
+			if methods[it].types == READER {
 				if methods[it].status == true {
-					fmt.Printf("methods[%d].status == true (ABSENT)\n", it)
-					// promote reads
-					//if items.items[itItems].(*Item).sum > 0 {
-					if items[itItems].sum > 0 {
-						//items.items[itItems].(*Item).sumR = 0
-						items[itItems].sumR = 0
+					if items[itItems].status == ABSENT || items[itItems].sum <= 0 {
+						// read a value that is not there, legal only if a write overlaps it
+						items[itItems].demoteReader()
+						items[itItems].readMethods = append(items[itItems].readMethods, &methods[it])
 					}
+				} else {
+					failConsumer(methods, items, it, &stackFailed)
+				}
+			}
 
-					//items[itItems].subInt(1)
-					fmt.Printf("subInt at items[%d] which has key %s, resulting in sum of %f\n", itItems, items[itItems].key, items[itItems].sum)
-					//items.items[itItems].(*Item).status = ABSENT
+			if methods[it].types == CONSUMER {
+				if methods[it].status == true {
+					items[itItems].subInt(1)
 					items[itItems].status = ABSENT
+					items[itItems].consumer = it
+
+					// every demotion still outstanding is an item that should have
+					// been consumed first, relaxed semantics tolerate up to k of them
+					rank := int(math.Max(items[itItems].exponent, 0))
+					recordRankError(methods[it].semantics, rank)
+					if rank <= relaxation[methods[it].semantics] {
+						for ; rank > 0; rank-- {
+							items[itItems].promote()
+						}
+						dropPromotions(items, itItems)
+					}
 
-					//if mapItems[itItems].sum < 0 {
-					//
-					//	for idx := 0; idx != len(mapItems[itItems].demoteMethods) - 1; idx++ {
-					//
-					//		if mapMethods[it].response < mapItems[itItems].demoteMethods[idx].invocation ||
-					//			mapItems[itItems].demoteMethods[idx].response < mapMethods[it].invocation{
-					//			// Methods do not overlap
-					//			// fmt.Println("NOTE: Methods do not overlap")
-					//		} else {
-					//			mapItems[itItems].promote()
-					//
-					//			// need to remove from promote list
-					//			itMthdItem := int64(mapItems[itItems].demoteMethods[idx].itemKey)
-					//			var temp stack.Stack
-					//
-					//			for mapItems[itMthdItem].promoteItems.Peek() != nil{
-					//
-					//				top := mapItems[itMthdItem].promoteItems.Peek()
-					//				if top != mapMethods[it].itemKey {
-					//					temp.Push(top)
-					//				}
-					//				mapItems[itMthdItem].promoteItems.Pop()
-					//				fmt.Println("stuck here?")
-					//			}
-					//			// TODO: swap mapItems[itMthdItem].promoteItems with temp stack
-					//
-					//			//
-					//			mapItems[itItems].demoteMethods = reslice(mapItems[itItems].demoteMethods, idx)
-					//		}
-					//	}
-					//}
-					stackConsumer.Push(itItems)
-					stackFinishedMethods.Push(it)
-
-					end = len(methods) - 1
-					//if items.items[itItems].(*Item).producer != end {
-					if items[itItems].producer != end {
-						//stackFinishedMethods.Push(items.items[itItems].(*Item).producer)
-						stackFinishedMethods.Push(items[itItems].producer)
+					// a failed consumer that overlaps this one may be ordered after it
+					for _, mf := range items[itItems].failedMethods {
+						if !precedes(mf, &methods[it]) {
+							items[itItems].promoteFailed()
+						}
 					}
+					items[itItems].failedMethods = nil
+
+					promoteItems(methods, items, itItems, it)
 				} else {
-					handleFailedConsumer(methods, items, it + 1, itItems, &stackFailed)
+					failConsumer(methods, items, it, &stackFailed)
 				}
 			}
 		}
@@ -758,131 +1003,198 @@ func verifyCheckpoint(methods []Method, items []Item, itStart *int, countIterate
 			*itStart--
 		}
 
-		//NEED TO FLAG ITEMS ASSOCIATED WITH CONSUMER METHODS AS ABSENT
-		for stackConsumer.Len() != 0 {
-
-			itTop, ok := stackConsumer.Peek().(int)
-			if !ok {
-				return
-			}
-
-			//for items.items[itTop].(*Item).promoteItems.Len() != 0 {
-			for items[itTop].promoteItems.Len() != 0 {
-				/*itemPromote := items.items[itTop].(*Item).promoteItems.Peek().(int)
-				itPromoteItem := itemPromote
-				items.items[itPromoteItem].(*Item).promote()
-				items.items[itTop].(*Item).promoteItems.Pop()*/
-				itemPromote := items[itTop].promoteItems.Peek().(int)
-				itPromoteItem := itemPromote
-				items[itPromoteItem].promote()
-				items[itTop].promoteItems.Pop()
-			}
-			stackConsumer.Pop()
-		}
-
-		for stackFailed.Len() != 0 {
-			fmt.Printf("stackFailed length non zero:\n")
-			for i := 0; i < stackFailed.Len(); i++ {
-				fmt.Println(stackFailed.Pop())
-			}
-			//itTop := stackFailed.Peek().(int)
-			/*if items[itTop].status == PRESENT {
-				//items.items[itTop].(*Item).demoteFailed()
-				items[itTop].demoteFailed()
-			}
-			stackFailed.Pop()*/
-			temp := stackFailed.Peek().(string)
-			for itTop := range items {
-
-				//if items.items[itTop].(*Item).status == PRESENT {
-				if items[itTop].key == temp && items[itTop].status == PRESENT {
-					//items.items[itTop].(*Item).demoteFailed()
-					fmt.Printf("Demoting item...\n")
-					items[itTop].demoteFailed()
-				}
-				stackFailed.Pop()
-			}
-		}
-
-		// remove methods that are no longer active
-		//TODO: DANGER, this is the removal optimization that can cause segfaults, commented out the dangerous contents for now.
-		for stackFinishedMethods.Len() != 0 {
-			//itTop := stackFinishedMethods.Peek().(int64)
-			//(methods, itTop)
-			stackFinishedMethods.Pop()
-		}
-
 		// verify sums
 		outcome := true
-		itVerify := 0
-		//itEnd := len(items.items) - 1
-		itEnd := len(items)
+		finalViolations = nil
+		var negative, negativeR, negativeF int
 
-		if items[itVerify].sum < 0 {
-			fmt.Printf("Negative sum!\n")
-		}
-		for ; itVerify != itEnd; itVerify++ {
+		for itVerify := range items {
+			bad := false
 			if items[itVerify].sum < 0 {
 				outcome = false
-				// #if DEBUG_
-				//fmt.Printf("WARNING: Item %d, sum %.2f\n", items.items[itVerify].(*Item).key, items.items[itVerify].(*Item).sum)
-				fmt.Printf("WARNING1: Item %s (items[%d]), sum %.2f\n", items[itVerify].key, itVerify, items[itVerify].sum)
-				// #endif
+				bad = true
+				negative++
+				if logging(checkpointLog, LevelTrace) {
+					logAt(checkpointLog, LevelTrace, "negative sum", "item", items[itVerify].key, "sum", items[itVerify].sum)
+				}
 			}
 			//printf("Item %d, sum %.2lf\n", it_verify->second.key, it_verify->second.sum);
 
-			//if (math.Ceil(items.items[itVerify].(*Item).sum) + items.items[itVerify].(*Item).sumR) < 0 {
-			if (math.Ceil(items[itVerify].sum) + items[itVerify].sumR) < 0 {
+			// a read that no write could have been ordered before
+			if items[itVerify].sumR < 0 {
 				outcome = false
+				bad = true
+				negativeR++
 
-				// #if DEBUG_
-				//fmt.Printf("WARNING: Item %d, sum_r %.2f\n", items.items[itVerify].(*Item).key, items.items[itVerify].(*Item).sumR)
-				fmt.Printf("WARNING2: Item %s, sum_r %.2f\n", items[itVerify].key, items[itVerify].sumR)
-				// #endif
-			}
-
-			var n float64
-			//if items.items[itVerify].(*Item).sumF == 0 {
-			if items[itVerify].sumF == 0 {
-				n = 0
-			} else {
-				n = -1
+				if logging(checkpointLog, LevelTrace) {
+					logAt(checkpointLog, LevelTrace, "negative sum_r", "item", items[itVerify].key, "sum_r", items[itVerify].sumR)
+				}
 			}
 
-			//if (math.Ceil(items.items[itVerify].(*Item).sum)+items.items[itVerify].(*Item).sumF)*n < 0 {
-			fmt.Printf("prior to outcome = false, at items[%d] key = %s, sum = %f and sumF = %f and sumR = %f and n = %v and outcome = %b\n",itVerify, items[itVerify].key, items[itVerify].sum, items[itVerify].sumF, items[itVerify].sumR, n, outcome)
-			if (math.Ceil(items[itVerify].sum)+items[itVerify].sumF)*n < 0 {
-				fmt.Printf("!!!!!!!!!!\n")
+			// a failed consumer that was not reordered past this item's consumer
+			if items[itVerify].sumF < 0 {
 				outcome = false
-				// #if DEBUG_
-				//fmt.Printf("WARNING: Item %d, sum_f %.2f\n", items.items[itVerify].(*Item).key, items.items[itVerify].(*Item).sumF)
-				fmt.Printf("WARNING: Item %d, sum_f %.2f\n", items[itVerify].key, items[itVerify].sumF)
-				// #endif
+				bad = true
+				negativeF++
+				if logging(checkpointLog, LevelTrace) {
+					logAt(checkpointLog, LevelTrace, "negative sum_f", "item", items[itVerify].key, "sum_f", items[itVerify].sumF)
+				}
 			}
 
+			if bad {
+				finalViolations = append(finalViolations, items[itVerify].key)
+			}
 		}
 		if outcome == true {
 			finalOutcome = true
-			// #if DEBUG_
-			 fmt.Println("-------------Program Correct Up To This Point-------------")
-			// #endif
 		} else {
 			finalOutcome = false
+		}
+		checkpointsVerified.Inc()
+		itemCount.Set(float64(len(items)))
+		negativeSums.WithLabelValues("sum").Set(float64(negative))
+		negativeSums.WithLabelValues("sum_r").Set(float64(negativeR))
+		negativeSums.WithLabelValues("sum_f").Set(float64(negativeF))
+		checkpointSeconds.Observe(time.Since(began).Seconds())
+		if logging(checkpointLog, LevelTrace) {
+			logAt(checkpointLog, LevelTrace, "checkpoint verified", "methods", len(methods), "correct", outcome, "violations", finalViolations)
+		}
+	}
+	return nil
+}
+
+// verifyBlocks runs one checkpoint per quiescent block that has not been
+// verified yet, so a failure shows up in the block it happened in. saved,
+// if not nil, is called after each block with the methods verified so far.
+// It returns how many methods the finished checkpoints cover, and ctx's
+// error if it stopped before the end.
+func verifyBlocks(ctx context.Context, methods []Method, items []Item, itStart *int, countIterated *uint64, blocks []Block, saved func(verified int)) (int, error) {
+	next := 0
+	if *countIterated != 0 {
+		next = *itStart + 1
+	}
 
-			// #if DEBUG_
-			 fmt.Println("-------------Program Not Correct-------------")
-			// #endif
+	// in response order every block is a contiguous run of methods
+	sort.SliceStable(methods[next:], func(a, b int) bool {
+		return methods[next+a].response < methods[next+b].response
+	})
+
+	for next < len(methods) {
+		itB := methods[next].quiescentPeriod
+		end := next
+		for end < len(methods) && methods[end].quiescentPeriod == itB {
+			end++
 		}
+
+		failed := make(map[string]bool)
+		for _, key := range finalViolations {
+			failed[key] = true
+		}
+
+		if err := verifyCheckpoint(ctx, methods[:end], items, itStart, countIterated, blocks[itB].finish, false, blocks); err != nil {
+			return next, err
+		}
+
+		// a block is judged on the items it broke, not on earlier blocks'
+		blocks[itB].methods += end - next
+		for _, key := range finalViolations {
+			if !failed[key] {
+				blocks[itB].violations = append(blocks[itB].violations, key)
+			}
+		}
+		blocks[itB].correct = len(blocks[itB].violations) == 0
+		next = end
+		if saved != nil {
+			saved(end)
+		}
+	}
+	return next, nil
+}
+
+type Verdict struct {
+	correct    bool
+	violations []string // keys of the violating items
+	rankErrors []int    // consumers per observed rank error
+	blocks     []Block
+
+	// a verification stopped early is correct up to its first verified methods
+	verified int
+	stopped  error
+}
+
+// verifyHistory runs the checkpoint verifier over a complete history
+func verifyHistory(methods []Method) Verdict {
+	return verifyHistoryContext(context.Background(), methods)
+}
+
+// verifyHistoryContext is verifyHistory that stops early once ctx is done
+func verifyHistoryContext(ctx context.Context, methods []Method) Verdict {
+	v, _ := verifyHistoryItems(ctx, methods)
+	return v
+}
+
+// verifyHistoryItems is verifyHistoryContext that also returns the final items
+func verifyHistoryItems(ctx context.Context, methods []Method) (Verdict, []Item) {
+	items := make([]Item, 0, len(methods))
+	for i := range methods {
+		if findItem(items, methods[i].itemAddrS) == -1 {
+			var item Item
+			item.setItem(methods[i].itemAddrS)
+			items = append(items, item)
+		}
+	}
+
+	var itStart int
+	var countIterated uint64 = 0
+
+	blocks := detectBlocks(methods)
+
+	finalOutcome = true
+	finalViolations = nil
+	rankErrors = nil
+	verified, err := verifyBlocks(ctx, methods, items, &itStart, &countIterated, blocks, nil)
+
+	return Verdict{finalOutcome, finalViolations, rankErrors, blocks, verified, err}, items
+}
+
+// reportVerdict prints the verdict of an imported history the way main does
+// and returns whether it was correct
+func reportVerdict(v Verdict) bool {
+	switch {
+	case !v.correct:
+		fmt.Printf("-------------Program Not Correct-------------\n")
+		fmt.Printf("Violating items: %v\n", v.violations)
+	case v.stopped != nil:
+		fmt.Printf("-------------Program Correct Up To Method %d-------------\n", v.verified)
+	default:
+		fmt.Printf("-------------Program Correct Up To This Point-------------\n")
 	}
+	if v.stopped != nil {
+		fmt.Printf("Stopped after verifying %d methods: %v\n", v.verified, v.stopped)
+	}
+	fmt.Printf("Max rank error: %d, rank errors: %v\n", maxRankError(v.rankErrors), v.rankErrors)
+	return v.correct
 }
 
-func work(id int, doneWG *sync.WaitGroup) {
+func work(ctx context.Context, id int, doneWG *sync.WaitGroup) {
+	defer doneWG.Done()
+	defer finishThread()
+
+	// the threads start together
+	if wait(ctx) != nil {
+		return
+	}
+
 	//fmt.Printf("%d is working!!", id)
-	testSize := int32(1)
+	// this thread's share of the transactions
+	testSize := int32(len(transactions) / numThreads)
+	if id < len(transactions)%numThreads {
+		testSize++
+	}
 	wallTime := 0.0
 	var tod syscall.Timeval
 	if err := syscall.Gettimeofday(&tod); err != nil {
-		fmt.Println("Error: get time of day")
+		logAt(workerLog, slog.LevelError, "get time of day", "thread", id, "err", err)
 		return
 	}
 	wallTime += float64(tod.Sec)
@@ -897,38 +1209,98 @@ func work(id int, doneWG *sync.WaitGroup) {
 	//startTime := time.Unix(0, start.UnixNano())
 	//startTimeEpoch := time.Since(startTime)
 	//
-	txnCtr.lock.Lock()
-	mId := txnCtr.val *2
+	// method ids are unique across the threads
+	mId := int64(id)
 	//Atomic.AddInt64(&txnCtr.val, 1)
-	txnCtr.lock.Unlock()
 	//
 	//var end time.Time
 
-	//wait()
 	//if(Atomic.LoadInt32(&numTxns) == 0) {
-		//return;
+	//return;
 	//}
 
-	if numTxns == 0 {
-		done[id].Store(true)
-		doneWG.Done()
-		return
-	} else {
-		Atomic.AddInt32(&numTxns, -1)
+	// transfers wait for their ledger block to commit
+	block := make([]blockTransfer, 0, ledgerBlockSize)
+	commit := func() {
+		if len(block) == 0 {
+			return
+		}
+		response := commitBlock(block)
+		Atomic.AddInt64(&methodTime[id], response-block[0].invocation)
+
+		for _, t := range block {
+			txn := t.txn
+			if logging(workerLog, slog.LevelDebug) {
+				logAt(workerLog, slog.LevelDebug, "transaction", "thread", id, "sender", txn.addrSender, "receiver", txn.addrReceiver, "amount", txn.amount, "nonce", txn.nonce, "committed", t.committed)
+			}
+			var m1 Method
+			m1.setMethod(int(mId), txn.addrSender, txn.addrReceiver, t.balanceSender, FIFO, PRODUCER, t.committed, int(mId), txn.amount, txn.tId)
+			m1.invocation = t.invocation
+			m1.response = response
+			m1.process = id
+			m1.nonce = txn.nonce
+
+			// account being subtracted from
+			Atomic.AddInt64(&mId, numThreads)
+			var m2 Method
+			m2.setMethod(int(mId), txn.addrSender, txn.addrReceiver, t.balanceReceiver, FIFO, CONSUMER, t.committed, int(mId), -txn.amount, txn.tId)
+			m2.invocation = t.invocation
+			m2.response = response
+			m2.process = id
+			m2.nonce = txn.nonce
+			Atomic.AddInt64(&mId, numThreads)
+
+			record(id, m1, m2)
+			logMethods(id, &m1, &m2)
+		}
+		Atomic.AddInt64(&overheadTime[id], time.Since(start).Nanoseconds()-response)
+		block = block[:0]
 	}
 
 	for i := int32(0); i < testSize; i++ {
 
-		/*if Atomic.LoadInt32(&numTxns) == 0 {
-			break;
-		}*/
-		var res bool
+		if ctx.Err() != nil || Atomic.AddInt32(&numTxns, -1) < 0 {
+			break
+		}
+
 		txnCtr.lock.Lock()
-		itemAddr1 := transactions[Atomic.LoadInt64(&txnCtr.val)].addrSender
-		itemAddr2 := transactions[Atomic.LoadInt64(&txnCtr.val)].addrReceiver
-		amount := transactions[Atomic.LoadInt64(&txnCtr.val)].amount
+		txn := transactions[Atomic.LoadInt64(&txnCtr.val)]
 		Atomic.AddInt64(&txnCtr.val, 1)
 		txnCtr.lock.Unlock()
+		itemAddr1, amount := txn.addrSender, txn.amount
+
+		// reads and writes go to the sender's memo register, after the
+		// thread's transfers so far
+		if txn.types == READER || txn.types == WRITER {
+			commit()
+			key := memoKey(itemAddr1)
+			invocation := time.Since(start).Nanoseconds()
+			value, ok := amount, true
+			memoLock.Lock()
+			if txn.types == READER {
+				value, ok = memos[key]
+			} else {
+				memos[key] = amount
+			}
+			memoLock.Unlock()
+			response := time.Since(start).Nanoseconds()
+			Atomic.AddInt64(&methodTime[id], response-invocation)
+
+			if logging(workerLog, slog.LevelDebug) {
+				logAt(workerLog, slog.LevelDebug, txn.types.String(), "thread", id, "key", key, "value", value, "ok", ok)
+			}
+			var m Method
+			m.setMethod(int(mId), key, "", value, MAPP, txn.types, ok, int(mId), 0, txn.tId)
+			m.invocation = invocation
+			m.response = response
+			m.process = id
+			Atomic.AddInt64(&mId, numThreads)
+
+			record(id, m)
+			logMethods(id, &m)
+			Atomic.AddInt64(&overheadTime[id], time.Since(start).Nanoseconds()-response)
+			continue
+		}
 		//opDist := uint32(1 + randDistOp.Intn(100))  // uniformly distributed pseudo-random number between 1 - 100 ??
 
 		//end = time.Now()
@@ -986,25 +1358,11 @@ func work(id int, doneWG *sync.WaitGroup) {
 		//response := post_function_epoch.count() - start_time_epoch.count()
 		//response := postFunctionEpoch - startTimeEpoch.Nanoseconds()
 
-
-		if allSenders[itemAddr1] == 1 {
-			allSenders[itemAddr1] = 0
-			res = false
-		} else {
-			allSenders[itemAddr1] = 1
-			res = true
+		block = append(block, blockTransfer{txn: txn, invocation: time.Since(start).Nanoseconds()})
+		if len(block) == ledgerBlockSize {
+			commit()
 		}
 
-		fmt.Printf("res for %s is %v\n", itemAddr1, res)
-		var m1 Method
-		m1.setMethod(int(mId), itemAddr1, itemAddr2, transactions[id].balanceSender, FIFO, PRODUCER, res, int(mId), amount, transactions[id].tId)
-
-		// account being subtracted from
-		Atomic.AddInt64(&mId, 1)
-		var m2 Method
-		m2.setMethod(int(mId),itemAddr1, itemAddr2, transactions[id].balanceReceiver, FIFO, CONSUMER, res, int(mId), -amount, transactions[id].tId)
-		Atomic.AddInt64(&mId, 1)
-
 		//Atomic.AddInt32(&numTxns, -1)
 		// mId += numThreads
 
@@ -1022,68 +1380,60 @@ func work(id int, doneWG *sync.WaitGroup) {
 		}*/
 		//threadLists.Lock()
 		//TODO: we want to append both...right?
-		threadLists.items[id] = append(threadLists.items[id].([]Method), m1)
-		threadLists.items[id] = append(threadLists.items[id].([]Method), m2)
 		//fmt.Printf("threadlist %d: %v\n", id, threadLists.items[id])
-		threadListsSize[id].Add(1)
-		Atomic.AddInt64(&methodTime[id], 1)
 		//threadLists.Unlock()
 	}
-
-	done[id].Store(true)
-	doneWG.Done()
+	commit()
 }
 
-func verify(doneWG *sync.WaitGroup) {
+// verify collects the threads' methods and verifies them until every thread
+// is done, or until ctx is, which it records in stopped
+func verify(ctx context.Context, doneWG *sync.WaitGroup) {
 	//defer processTimer(time.Now(), &txnCtr.val)
-	fmt.Println("Verifying...")
-	//wait()
+	logAt(collectorLog, slog.LevelInfo, "verifying")
 	var countIterated uint64 = 0
 
-	startTime := time.Unix(0, start.UnixNano())
-	startTimeEpoch := time.Since(startTime)
-
-	end := time.Now()
-
-	preVerify := time.Unix(0, end.UnixNano())
-	preVerifyEpoch := time.Since(preVerify)
-
-	verifyStart := preVerifyEpoch.Nanoseconds() - startTimeEpoch.Nanoseconds()
-
+	verifyStart := time.Since(start).Nanoseconds()
 
 	// fnPt       := fncomp
 	methods := make([]Method, 0)
 	//methods := NewConcurrentSlice()
 	blocks := make([]Block, 0)
 	//items := make([]Item, 0, numTxns * 2)
-	fmt.Printf("txnCtr is %v\n", txnCtr.val)
-	items := make([]Item, 0, txnCtr.val * 2)
+	logAt(collectorLog, slog.LevelDebug, "transactions", "count", len(transactions))
+	items := make([]Item, 0, len(transactions)*2)
 	it := make([]int, numThreads, numThreads)
 	var itStart int
+	inBlocks := 0 // methods stamped with their block
 
 	stop := false
 	var countOverall uint32 = 0
 
-	var min int64
+	//var min int64
 	//var oldMin int64
 	var itCount [numThreads]int32
 
 	// std::map<long int,Method,bool(*)(long int,long int)>::iterator it_qstart;
 
+	defer broadcastOnDone(ctx)()
 	for {
 		if stop {
 			break
 		}
 
-		stop = true
-		min = math.MaxInt64
+		// sleep until a thread records methods or every thread is done
+		threadLists.Lock()
+		for running > 0 && !pending(itCount[:]) && ctx.Err() == nil {
+			collected.Wait()
+		}
+		stop = running == 0
+		if stopped = ctx.Err(); stopped != nil {
+			threadLists.Unlock()
+			break
+		}
+		//min = math.MaxInt64
 
 		for i := 0; i < numThreads; i++ {
-			if done[i].Load() == false {
-
-				stop = false
-			}
-
 			tId := i
 
 			// TODO: Correctness not based on time any more, so do we still need response field?
@@ -1091,35 +1441,30 @@ func verify(doneWG *sync.WaitGroup) {
 
 			for {
 				//threadLists.Lock()
-				fmt.Printf("itCount[%d]: %d\tthreadListsSize[%d]: %d\n", i, itCount[i], i, threadListsSize[i].Load())
-				if threadListsSize[i].Load() > 0 {
-
+				if logging(collectorLog, LevelTrace) {
+					logAt(collectorLog, LevelTrace, "thread list", "thread", i, "itCount", itCount[i], "size", threadListsSize[i].Load())
 				}
+				// threadListsSize counts methods, a transfer records two
 				if itCount[i] >= threadListsSize[i].Load() {
 					break
-				} else if itCount[i] == 0 {
-					it[i] = 0 //threadLists[i].Front()
-				} else {
-					//++it[i]
-					it[i]++
 				}
+				it[i] = int(itCount[i])
 				//fmt.Printf("it[i] = %v\n", it[i])
 
 				var m Method
-				var m2 Method
 
-				//if it[i] < len(threadLists.items[tId].([]Method)) {
-				if it[i] < int(threadListsSize[tId].Load()) {
-					fmt.Printf("Address of methods txn sender at thread %d index %d: %s and at index %d: %s\n", i, it[i], threadLists.items[tId].([]Method)[it[i]].itemAddrS, it[i] + 1, threadLists.items[tId].([]Method)[it[i] + 1].itemAddrS)
-					fmt.Printf("%v\n", threadLists.items[i].([]Method))
+				if it[i] < len(threadLists.items[tId].([]Method)) {
+					if logging(collectorLog, LevelTrace) {
+						logAt(collectorLog, LevelTrace, "collecting", "thread", i, "index", it[i], "methods", fmt.Sprint(threadLists.items[i].([]Method)))
+					}
 					m = threadLists.items[tId].([]Method)[it[i]]
-					it[i]++
-					m2 = threadLists.items[tId].([]Method)[it[i]]
 				} else {
-					fmt.Printf("Verifier threadlist error!\n")
-					break;
+					logAt(collectorLog, slog.LevelError, "thread list shorter than its size", "thread", i, "index", it[i])
+					break
+				}
+				if logging(collectorLog, LevelTrace) {
+					logAt(collectorLog, LevelTrace, "collected", "thread", i, "m", m.itemAddrS, "type", m.types)
 				}
-				fmt.Printf("m address = %s\nm2 address = %s\n", m.itemAddrS, m2.itemAddrS)
 				//threadLists.Unlock()
 
 				/*mapMethodsEnd, err := findMethodKey(mapMethods, "end")
@@ -1145,7 +1490,6 @@ func verify(doneWG *sync.WaitGroup) {
 
 				//methods.Append(m)
 				methods = append(methods, m)
-				methods = append(methods, m2)
 
 				itCount[i]++
 				countOverall++
@@ -1154,48 +1498,15 @@ func verify(doneWG *sync.WaitGroup) {
 				//itItem := findIndexForMethod(methods, m, "itemAddr")
 				// itItem, _ := findMethodKey(mapMethods, m.itemAddr)
 
-				itItem := 0
-				//for i := range items {
-				for range items {
-					/*if items[i].key == m.itemAddrS {
-						break
-					}*/
-					itItem++
-				}
-
-
-				mapItemsEnd := len(items)
-				mapMethodsEnd := 0
-				for range methods {
-					mapMethodsEnd++
-				}
-				fmt.Printf("%d\t%d\n", itItem, mapItemsEnd)
-				if itItem == mapItemsEnd {
+				// one item per key, a transfer's two methods share the sender's
+				if findItem(items, m.itemAddrS) == -1 {
 					var item Item
-					var item2 Item
-					fmt.Printf("appending addresses to items: %v\t%v\n", m.itemAddrS, m2.itemAddrS)
+					if logging(collectorLog, LevelTrace) {
+						logAt(collectorLog, LevelTrace, "new item", "key", m.itemAddrS)
+					}
 					item.setItem(m.itemAddrS)
-					item2.setItem(m2.itemAddrS)
-					//item.key = m.itemAddr
-					item.producer = mapMethodsEnd
-
-					//items.items[item.key] = &item
-
-					//items.Append(ConcurrentSliceItem{len(items.items), item})
 					items = append(items, item)
-					items = append(items, item2)
-					//items.Append(item)
-
-					//itItem, _ = findMethodKey(mapMethods, m.itemAddr)
-
-					for i := range methods {
-						if methods[i].itemAddrS == m.itemAddrS {
-							itItem = i
-						}
-					}
-				}/* else {
-					fmt.Print(items)
-				}*/
+				}
 			}
 
 			/*if responseTime < min {
@@ -1203,31 +1514,43 @@ func verify(doneWG *sync.WaitGroup) {
 			}
 			*/
 		}
+		threadLists.Unlock()
 
-		verifyCheckpoint(methods, items, &itStart, &countIterated, int64(min), true, blocks)
+		// the blocks verified in earlier rounds keep their stats
+		blocks = extendBlocks(methods, inBlocks, blocks)
+		inBlocks = len(methods)
+		if verified, stopped = verifyBlocks(ctx, methods, items, &itStart, &countIterated, blocks, nil); stopped != nil {
+			break
+		}
+		if len(methods) != 0 {
+			observeLag(methods[len(methods)-1].response)
+		}
 
 	}
 
-	verifyCheckpoint(methods, items, &itStart, &countIterated, math.MaxInt64, false, blocks)
+	if stopped == nil {
+		stopped = verifyCheckpoint(ctx, methods, items, &itStart, &countIterated, math.MaxInt64, false, blocks)
+		if stopped == nil {
+			verified = len(methods)
+		}
+	}
 
-			//#if DEBUG_
-				fmt.Printf("Count overall = %v, count iterated = %d, methods size = %d, items size = %d\n", fmt.Sprint(countOverall), countIterated, len(methods), len(items));
-			//#endif
+	ledger := checkLedger(methods, genesis)
+	recorded = methods
 
-		//#if DEBUG_
-			fmt.Printf("All threads finished!\n")
+	if logging(collectorLog, LevelTrace) {
+		logAt(collectorLog, LevelTrace, "all threads finished", "countOverall", countOverall, "countIterated", countIterated, "methods", len(methods), "items", len(items))
+	}
 
-/*
-		itB, err := findBlockKey(mapBlock, "begin")
-		itBEnd, err2 := findBlockKey(mapBlock, "end")
-		if err != nil || err2 != nil {
-			return
-		}
+	for itB := range blocks {
+		fmt.Printf("Block start = %d, finish = %d, methods = %d, correct = %v, new violations = %v\n", blocks[itB].start, blocks[itB].finish, blocks[itB].methods, blocks[itB].correct, blocks[itB].violations)
+	}
 
-		for ; itB != itBEnd; itB++ {
-			fmt.Printf("Block start = %d, finish = %d\n", mapBlock[itB].start, mapBlock[itB].finish)
-		}
+	for itB := range ledger {
+		fmt.Printf("Ledger block start = %d, finish = %d, transactions = %d, root = %s, correct = %v, violations = %v\n", ledger[itB].start, ledger[itB].finish, len(ledger[itB].txns), ledger[itB].root, ledger[itB].correct, ledger[itB].violations)
+	}
 
+	/*
 		// How to??? line 1346
 		// std::map<long int,Method,bool(*)(long int,long int)>::iterator it_;
 
@@ -1247,31 +1570,104 @@ func verify(doneWG *sync.WaitGroup) {
 
 	// #endif
 
-	// end = std::chrono::high_resolution_clock::now();
-	// auto post_verify = std::chrono::time_point_cast<std::chrono::nanoseconds>(end);
-	end = time.Now()
-	postVerify := end.UnixNano()
-
-	postVerifyEpoch := time.Now().UnixNano() - postVerify
-	verifyFinish := postVerifyEpoch - startTimeEpoch.Nanoseconds()
-	//a := verifyStart / int64(time.Millisecond)
-	//b := verifyFinish / int64(time.Millisecond)
-	//fmt.Printf("verify start is %d verify finish is %d\n", a, b)
-
-	elapsedTimeVerify = verifyFinish - verifyStart
+	elapsedTimeVerify = time.Since(start).Nanoseconds() - verifyStart
 
 	doneWG.Done()
 }
 
-var transactions [200]TransactionData
-var allSenders map[string]int = make(map[string]int)
+var transactions []TransactionData
+var memos map[string]int = make(map[string]int)      // the accounts' memo registers
+var memoLock sync.Mutex                              // guards memos while the workers run
+var allSenders map[string]int = make(map[string]int) // next nonce of each sender
+var genesis map[string]int = make(map[string]int)    // balances before the first transaction
+var recorded []Method                                // the history verify checked
+var verified int                                     // methods verify got through
+var stopped error                                    // why verify stopped early, if it did
+var balances map[string]int = make(map[string]int)   // balances as the workers apply transactions
 var numTxns int32
 
+// verifyContext is done after timeout, if there is one, or on an interrupt
+func verifyContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	if timeout <= 0 {
+		return ctx, stop
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
 func main() {
 	// will use for i:= range threadLists.iter() in place of findMethodKey.
 	// Should we make methods, items, and blocks ConcurrentSliceItems or slap RWlocks around where we use them?
 	// Whats the deal with the separate items slice?
 	//allSenders := make(map[string]int)
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "serve":
+			serve(os.Args[2:])
+			return
+		case "jepsen":
+			jepsen(os.Args[2:])
+			return
+		case "porcupine":
+			porcupine(os.Args[2:])
+			return
+		case "eth":
+			eth(os.Args[2:])
+			return
+		case "audit":
+			audit(os.Args[2:])
+			return
+		case "bench":
+			bench(os.Args[2:])
+			return
+		case "check":
+			check(os.Args[2:])
+			return
+		case "render":
+			render(os.Args[2:])
+			return
+		}
+	}
+
+	conditionFlag := flag.String("condition", "linearizability", "correctness condition: linearizability, sequential or quiescent")
+	relaxFlag := flag.String("relax", "", "allowed rank error per semantics, e.g. fifo=2,priority=1")
+	historyFlag := flag.String("history", "", "write the verified history to this file as JSON lines")
+	reportFlag := flag.String("report", "", "write the verdict and the history's Merkle root to this file as JSON")
+	metricsFlag := flag.String("metrics", "", "serve Prometheus metrics on this address, e.g. :9100")
+	transactionsFlag := flag.Int("transactions", 32, "transactions to generate")
+	accountsFlag := flag.Int("accounts", 64, "accounts the transactions are between")
+	keysFlag := flag.String("keys", "uniform", "account distribution: uniform, zipf:S or hotspot:FRACTION:PROBABILITY")
+	amountsFlag := flag.String("amounts", "uniform:1:50", "amount distribution: fixed:N, uniform:LO:HI, zipf:S or hotspot:FRACTION:PROBABILITY")
+	mixFlag := flag.String("mix", "transfer=100,read=0,write=0", "operation mix weights")
+	seedFlag := flag.Int64("seed", 0, "workload seed, 0 for a random one")
+	binlogFlag := flag.String("binlog", "", "append every thread's methods to a binary log in this directory")
+	timeoutFlag := flag.Duration("timeout", 0, "stop working and verifying after this long and report how far verification got")
+	logFlag := flag.String("log", "", "log levels, e.g. debug or warn,checkpoint=trace (default $VERIFIER_LOG or warn)")
+	flag.Parse()
+
+	var err error
+	if condition, err = parseCondition(*conditionFlag); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if relaxation, err = parseRelaxation(*relaxFlag); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if err := configureLogging(*logFlag); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if *metricsFlag != "" {
+		serveMetrics(*metricsFlag)
+	}
+	ctx, cancel := verifyContext(*timeoutFlag)
+	defer cancel()
+
 	Atomic.StoreInt32(&numTxns, 0)
 
 	methodCount = 0
@@ -1279,78 +1675,75 @@ func main() {
 	finalOutcome = true
 
 	//threadLists := NewConcurrentSlice()
-	threadLists = ConcurrentSlice{items: make([]interface{}, 0, numThreads),}
+	threadLists = ConcurrentSlice{items: make([]interface{}, 0, numThreads)}
 
 	var doneWG sync.WaitGroup
+	running = numThreads
 
-// Generating transaction data
-	var hexRunes = []rune("0123456789abcdef")
-	var transactionSenders = make([]rune,16)
-	var transactionReceivers = make([]rune,16)
-	var control string
-
-	for i := 0; i < 32; i++ {
-		Atomic.AddInt32(&numTxns, 1)
-		for j := 0; j < 16; j++ {
-			transactionSenders[j] = hexRunes[rand.Intn(len(hexRunes))]
-			transactionReceivers[j] = hexRunes[rand.Intn(len(hexRunes))]
-		}
-
-		//fmt.Printf("%s\n", string(transactionSenders))
-
-		if i == 0 {
-			transactions[i].addrSender = string(transactionSenders)
-			transactions[i].addrReceiver = string(transactionReceivers)
-			control = transactions[i].addrSender
-			transactions[i].amount = 1
-		} else if i == 31 {
-			transactions[i].addrSender = control
-			//transactions[i].addrSender = string(transactionSenders)
-			transactions[i].addrReceiver = string(transactionReceivers)
-			transactions[i].amount = 51
-		} else {
-			transactions[i].addrSender = string(transactionSenders)
-			transactions[i].addrReceiver = string(transactionReceivers)
-			transactions[i].amount = 50 - int(Atomic.LoadInt32(&numTxns))
-		}
-		allSenders[string(transactionSenders)] = 0
-		//transactions[i].amount = rand.Intn(50)
-		/*if(i == 0) {
-			transactions[i].amount = 300
-		} else {
-			transactions[i].amount = 200
-		}*/
-		transactions[i].balanceSender = rand.Intn(50)
-		transactions[i].balanceReceiver = rand.Intn(50)
-		transactions[i].tId = Atomic.LoadInt32(&numTxns)
-		//Atomic.AddInt32(&txnCtr.val, 1)
+	// Generating transaction data
+	if *seedFlag == 0 {
+		*seedFlag = time.Now().UnixNano()
+	}
+	workload := workloadConfig{
+		transactions: *transactionsFlag,
+		accounts:     *accountsFlag,
+		keys:         *keysFlag,
+		amounts:      *amountsFlag,
+		seed:         *seedFlag,
+	}
+	err = workload.parseMix(*mixFlag)
+	if err == nil {
+		transactions, genesis, err = generateWorkload(workload)
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	Atomic.StoreInt32(&numTxns, int32(len(transactions)))
+	for a, v := range genesis {
+		balances[a] = v
 	}
 	txnCtr.val = 0
-	start := time.Now()
-	defer processTimer(time.Now(), &numTxns)
+	start = time.Now()
+	if *binlogFlag != "" {
+		if err := openBinlogs(*binlogFlag); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+	defer processTimer(time.Now(), &txnCtr.val)
 
 	//TODO: thread/ channel stuff
 
 	for i := 0; i < numThreads; i++ {
-
 		threadLists.Append(make([]Method, 0))
 		threadListsSize[i].Store(0)
+	}
+
+	// the workers meet at the barrier, the collector verifies while they run
+	for i := 0; i < numThreads; i++ {
 		doneWG.Add(1)
-		go work(i, &doneWG)
-		doneWG.Wait()
+		go work(ctx, i, &doneWG)
 	}
-	//doneWG.Wait()
 	doneWG.Add(1)
-	go verify(&doneWG)
+	go verify(ctx, &doneWG)
 	doneWG.Wait()
+	closeBinlogs()
 	fmt.Println("finished working and verifying!")
 
-	fmt.Printf("Control was: %s\n", control)
+	fmt.Printf("Workload seed: %d\n", *seedFlag)
 
-	if finalOutcome == true {
-		fmt.Printf("-------------Program Correct Up To This Point-------------\n")
-	} else {
-		fmt.Printf("-------------Program Not Correct-------------\n")
+	reportVerdict(Verdict{finalOutcome, finalViolations, rankErrors, nil, verified, stopped})
+
+	if *historyFlag != "" {
+		if err := writeHistory(*historyFlag, recorded); err != nil {
+			fmt.Println(err)
+		}
+	}
+	if *reportFlag != "" {
+		if err := writeReport(*reportFlag, newReport(recorded, genesis)); err != nil {
+			fmt.Println(err)
+		}
 	}
 
 	finish := time.Now()                                //auto finish = std::chrono::high_resolution_clock::now();
@@ -1359,27 +1752,15 @@ func main() {
 	var elapsedTimeDouble float64 = float64(elapsedTime) * 0.000000001
 	fmt.Printf("Total Time: %.15f seconds\n", elapsedTimeDouble)
 
-	var elapsedTimeMethod int64 = 0
-	var elapsedOverheadTime float64 = 0
-
-	for i := 0; i < numThreads; i++ {
-		if methodTime[i] > elapsedTimeMethod {
-			elapsedTimeMethod = methodTime[i]
-		}
-		//we don't change overheadTime[i] anywhere, neither did Christina by the looks of it
-		if float64(overheadTime[i]) > elapsedOverheadTime {
-			elapsedOverheadTime = float64(overheadTime[i])
-		}
-	}
-
-	var elapsedTimeMethodDouble float64 = float64(elapsedTimeMethod) * 0.000000001
-	//var elapsedOverheadTimeDouble float64 = elapsedOverheadTime * 0.000000001
+	// verify's time counts as overhead, running alongside the workers or not
+	elapsedTimeMethodDouble := maxThreadTime(methodTime[:])
+	elapsedOverheadTimeDouble := maxThreadTime(overheadTime[:])
 	var elapsedTimeVerifyDouble float64 = float64(elapsedTimeVerify) * 0.000000001
 
 	fmt.Printf("Total Method Time: %.15f seconds\n", elapsedTimeMethodDouble)
-	//fmt.Printf("Total Overhead Time: %.15f seconds\n", elapsedOverheadTimeDouble)
-
-	elapsedTimeVerifyDouble = elapsedTimeVerifyDouble - elapsedTimeMethodDouble
-
-	//fmt.Printf("Total Verification Time: %.15f seconds\n", elapsedTimeVerifyDouble)
-}
\ No newline at end of file
+	fmt.Printf("Total Overhead Time: %.15f seconds\n", elapsedOverheadTimeDouble)
+	fmt.Printf("Total Verification Time: %.15f seconds\n", elapsedTimeVerifyDouble)
+	if elapsedTimeMethodDouble > 0 {
+		fmt.Printf("Overhead: %.2fx method time\n", (elapsedOverheadTimeDouble+elapsedTimeVerifyDouble)/elapsedTimeMethodDouble)
+	}
+}
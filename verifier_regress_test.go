@@ -0,0 +1,101 @@
+package main
+
+import "testing"
+
+// histories the fuzz targets turned up, each of which panicked or got the
+// wrong verdict before items were looked up by key and methods applied in
+// response order against real time
+var regressions = []struct {
+	name    string
+	methods []Method
+	correct bool
+}{
+	{
+		// items were indexed by method, not by key, and ran out
+		"produce then consume",
+		[]Method{
+			{types: PRODUCER, semantics: FIFO, status: true, itemAddrS: "k0", invocation: 0, response: 1},
+			{types: CONSUMER, semantics: FIFO, status: true, itemAddrS: "k0", invocation: 2, response: 3},
+		},
+		true,
+	},
+	{
+		// three methods on a single item
+		"failed methods on one key",
+		[]Method{
+			{id: 0, types: PRODUCER, semantics: FIFO, status: false, itemAddrS: "k1", invocation: 0, response: 0},
+			{id: 1, types: CONSUMER, semantics: FIFO, status: false, itemAddrS: "k1", invocation: 0, response: 0},
+			{id: 2, types: CONSUMER, semantics: FIFO, status: true, itemAddrS: "k1", invocation: 3, response: 6},
+		},
+		true,
+	},
+	{
+		// consumers never took their item out of the sum
+		"dequeue past the head, then fail on a non-empty queue",
+		[]Method{
+			{id: 0, types: PRODUCER, semantics: FIFO, status: true, itemAddrS: "k0", invocation: 0, response: 1},
+			{id: 1, types: PRODUCER, semantics: FIFO, status: true, itemAddrS: "k1", invocation: 2, response: 3},
+			{id: 2, types: CONSUMER, semantics: FIFO, status: true, itemAddrS: "k1", invocation: 4, response: 5},
+			{id: 3, types: CONSUMER, semantics: FIFO, status: false, itemAddrS: "k2", invocation: 6, response: 7},
+		},
+		false,
+	},
+	{
+		"enqueue, dequeue",
+		[]Method{
+			{id: 0, types: PRODUCER, semantics: FIFO, status: true, itemAddrS: "p0", invocation: 0, response: 0},
+			{id: 1, types: CONSUMER, semantics: FIFO, status: true, itemAddrS: "p0", invocation: 4, response: 4},
+		},
+		true,
+	},
+	{
+		// the demotion loop indexed items by method
+		"FIFO reorder",
+		[]Method{
+			{id: 0, types: PRODUCER, semantics: FIFO, status: true, itemAddrS: "p0", invocation: 0, response: 0},
+			{id: 1, types: PRODUCER, semantics: FIFO, status: true, itemAddrS: "p1", invocation: 4, response: 4},
+			{id: 2, types: CONSUMER, semantics: FIFO, status: true, itemAddrS: "p1", invocation: 8, response: 8},
+			{id: 3, types: CONSUMER, semantics: FIFO, status: true, itemAddrS: "p0", invocation: 12, response: 12},
+		},
+		false,
+	},
+	{
+		// failed consumers were matched by request amount, not real time
+		"failed dequeue on a non-empty queue",
+		[]Method{
+			{id: 0, types: PRODUCER, semantics: FIFO, status: true, itemAddrS: "p0", invocation: 0, response: 0},
+			{id: 1, types: CONSUMER, semantics: FIFO, status: false, itemAddrS: "p0", invocation: 4, response: 4},
+		},
+		false,
+	},
+	{
+		// a consumer never took its item, so nothing went negative
+		"dequeue of a key never enqueued",
+		[]Method{
+			{id: 0, types: CONSUMER, semantics: FIFO, status: true, itemAddrS: "p0", invocation: 0, response: 0},
+		},
+		false,
+	},
+	{
+		// must not be demoted behind an enqueue it overlaps
+		"overlapping enqueues",
+		[]Method{
+			{id: 0, types: PRODUCER, semantics: FIFO, status: true, itemAddrS: "p0", invocation: 0, response: 7},
+			{id: 1, types: PRODUCER, semantics: FIFO, status: true, itemAddrS: "p1", invocation: 1, response: 8},
+			{id: 2, types: CONSUMER, semantics: FIFO, status: true, itemAddrS: "p1", invocation: 2, response: 9},
+		},
+		true,
+	},
+}
+
+func TestVerifyCheckpointRegressions(t *testing.T) {
+	for _, r := range regressions {
+		t.Run(r.name, func(t *testing.T) {
+			methods := append([]Method(nil), r.methods...)
+			verifyHistory(methods)
+			if finalOutcome != r.correct {
+				t.Errorf("correct = %v, want %v", finalOutcome, r.correct)
+			}
+		})
+	}
+}
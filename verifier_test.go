@@ -1,32 +1,644 @@
 package main
 
 import (
-	"math/rand"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 )
 
-type Data struct {
-	sender string
-	receiver string
-	amount int
+var update = flag.Bool("update", false, "rewrite the golden files under testdata/")
+
+// op builds one method of a hand-written history
+func op(types Types, semantics Semantics, key string, status bool, invocation int64, response int64) Method {
+	return opValue(types, semantics, key, 0, status, invocation, response)
 }
 
-var data []Data
+func opValue(types Types, semantics Semantics, key string, value int, status bool, invocation int64, response int64) Method {
+	var m Method
+	m.setMethod(0, key, "", value, semantics, types, status, 0, 0, 0)
+	m.invocation = invocation
+	m.response = response
+	return m
+}
 
 func TestVerifier(t *testing.T) {
-	// Generating 50 random transactions
-	var hexRunes = []rune("123456789abcdef")
-	var transactionSenders = make([]rune,16)
-	var transactionReceivers = make([]rune,16)
-	//var transactions [50]TransactionData
-
-	for i := 0; i < 50; i++ {
-		for j := 0; j < 16; j++ {
-			transactionSenders[j] = hexRunes[rand.Intn(len(hexRunes))]
-			transactionReceivers[j] = hexRunes[rand.Intn(len(hexRunes))]
-		}
-		data[i].sender = string(transactionSenders)
-		data[i].receiver = string(transactionReceivers)
-		data[i].amount = rand.Intn(50)
+	tests := []struct {
+		name    string
+		k       int // relaxation applied to every semantics
+		methods []Method
+	}{
+		{"fifo_correct", 0, []Method{
+			op(PRODUCER, FIFO, "a", true, 0, 1),
+			op(PRODUCER, FIFO, "b", true, 2, 3),
+			op(CONSUMER, FIFO, "a", true, 4, 5),
+			op(CONSUMER, FIFO, "b", true, 6, 7),
+		}},
+		{"fifo_reorder", 0, []Method{
+			op(PRODUCER, FIFO, "a", true, 0, 1),
+			op(PRODUCER, FIFO, "b", true, 2, 3),
+			op(CONSUMER, FIFO, "b", true, 4, 5),
+			op(CONSUMER, FIFO, "a", true, 6, 7),
+		}},
+		{"fifo_overlapping_reorder", 0, []Method{
+			op(PRODUCER, FIFO, "a", true, 0, 3),
+			op(PRODUCER, FIFO, "b", true, 1, 2),
+			op(CONSUMER, FIFO, "b", true, 4, 5),
+			op(CONSUMER, FIFO, "a", true, 6, 7),
+		}},
+		{"fifo_failed_consumer_nonempty", 0, []Method{
+			op(PRODUCER, FIFO, "a", true, 0, 1),
+			op(CONSUMER, FIFO, "", false, 2, 3),
+		}},
+		{"fifo_failed_consumer_empty", 0, []Method{
+			op(CONSUMER, FIFO, "", false, 0, 1),
+			op(PRODUCER, FIFO, "a", true, 2, 3),
+			op(CONSUMER, FIFO, "a", true, 4, 5),
+		}},
+		{"fifo_failed_consumer_overlapping", 0, []Method{
+			op(PRODUCER, FIFO, "a", true, 0, 1),
+			op(CONSUMER, FIFO, "", false, 2, 5),
+			op(CONSUMER, FIFO, "a", true, 3, 4),
+		}},
+		{"fifo_consume_never_produced", 0, []Method{
+			op(CONSUMER, FIFO, "x", true, 0, 1),
+		}},
+		{"fifo_double_consume", 0, []Method{
+			op(PRODUCER, FIFO, "a", true, 0, 1),
+			op(CONSUMER, FIFO, "a", true, 2, 3),
+			op(CONSUMER, FIFO, "a", true, 4, 5),
+		}},
+		{"fifo_consumed_before_produced", 0, []Method{
+			op(CONSUMER, FIFO, "a", true, 0, 1),
+			op(PRODUCER, FIFO, "a", true, 2, 3),
+		}},
+		{"lifo_correct", 0, []Method{
+			op(PRODUCER, LIFO, "a", true, 0, 1),
+			op(PRODUCER, LIFO, "b", true, 2, 3),
+			op(CONSUMER, LIFO, "b", true, 4, 5),
+			op(CONSUMER, LIFO, "a", true, 6, 7),
+		}},
+		{"lifo_reorder", 0, []Method{
+			op(PRODUCER, LIFO, "a", true, 0, 1),
+			op(PRODUCER, LIFO, "b", true, 2, 3),
+			op(CONSUMER, LIFO, "a", true, 4, 5),
+			op(CONSUMER, LIFO, "b", true, 6, 7),
+		}},
+		{"lifo_failed_consumer_nonempty", 0, []Method{
+			op(PRODUCER, LIFO, "a", true, 0, 1),
+			op(CONSUMER, LIFO, "", false, 2, 3),
+		}},
+		{"kfifo_within_bound", 1, []Method{
+			op(PRODUCER, FIFO, "a", true, 0, 1),
+			op(PRODUCER, FIFO, "b", true, 2, 3),
+			op(CONSUMER, FIFO, "b", true, 4, 5),
+			op(CONSUMER, FIFO, "a", true, 6, 7),
+		}},
+		{"kfifo_beyond_bound", 1, []Method{
+			op(PRODUCER, FIFO, "a", true, 0, 1),
+			op(PRODUCER, FIFO, "b", true, 2, 3),
+			op(PRODUCER, FIFO, "c", true, 4, 5),
+			op(CONSUMER, FIFO, "c", true, 6, 7),
+			op(CONSUMER, FIFO, "a", true, 8, 9),
+			op(CONSUMER, FIFO, "b", true, 10, 11),
+		}},
+		{"kfifo_double_dequeue", 1, []Method{
+			op(PRODUCER, FIFO, "a", true, 0, 1),
+			op(PRODUCER, FIFO, "b", true, 2, 3),
+			op(CONSUMER, FIFO, "b", true, 4, 8),
+			op(CONSUMER, FIFO, "a", true, 5, 9),
+			op(CONSUMER, FIFO, "b", true, 10, 11),
+		}},
+		{"klifo_within_bound", 2, []Method{
+			op(PRODUCER, LIFO, "a", true, 0, 1),
+			op(PRODUCER, LIFO, "b", true, 2, 3),
+			op(PRODUCER, LIFO, "c", true, 4, 5),
+			op(CONSUMER, LIFO, "a", true, 6, 7),
+			op(CONSUMER, LIFO, "c", true, 8, 9),
+			op(CONSUMER, LIFO, "b", true, 10, 11),
+		}},
+		{"priority_correct", 0, []Method{
+			opValue(PRODUCER, PRIORITY, "a", 5, true, 0, 1),
+			opValue(PRODUCER, PRIORITY, "b", 1, true, 2, 3),
+			opValue(PRODUCER, PRIORITY, "c", 3, true, 4, 5),
+			opValue(CONSUMER, PRIORITY, "b", 1, true, 6, 7),
+			opValue(CONSUMER, PRIORITY, "c", 3, true, 8, 9),
+			opValue(CONSUMER, PRIORITY, "a", 5, true, 10, 11),
+		}},
+		{"priority_reorder", 0, []Method{
+			opValue(PRODUCER, PRIORITY, "a", 5, true, 0, 1),
+			opValue(PRODUCER, PRIORITY, "b", 1, true, 2, 3),
+			opValue(CONSUMER, PRIORITY, "a", 5, true, 4, 5),
+			opValue(CONSUMER, PRIORITY, "b", 1, true, 6, 7),
+		}},
+		{"kpriority_within_bound", 1, []Method{
+			opValue(PRODUCER, PRIORITY, "a", 5, true, 0, 1),
+			opValue(PRODUCER, PRIORITY, "b", 1, true, 2, 3),
+			opValue(CONSUMER, PRIORITY, "a", 5, true, 4, 5),
+			opValue(CONSUMER, PRIORITY, "b", 1, true, 6, 7),
+		}},
+		{"set_any_order", 0, []Method{
+			op(PRODUCER, SET, "a", true, 0, 1),
+			op(PRODUCER, SET, "b", true, 2, 3),
+			op(CONSUMER, SET, "b", true, 4, 5),
+			op(CONSUMER, SET, "a", true, 6, 7),
+		}},
+		{"set_failed_remove_absent", 0, []Method{
+			op(PRODUCER, SET, "a", true, 0, 1),
+			op(CONSUMER, SET, "b", false, 2, 3),
+		}},
+		{"set_failed_remove_present", 0, []Method{
+			op(PRODUCER, SET, "a", true, 0, 1),
+			op(PRODUCER, SET, "b", true, 2, 3),
+			op(CONSUMER, SET, "a", false, 4, 5),
+		}},
+		{"set_contains_removed", 0, []Method{
+			op(PRODUCER, SET, "a", true, 0, 1),
+			op(CONSUMER, SET, "a", true, 2, 3),
+			op(READER, SET, "a", true, 4, 5),
+		}},
+		{"map_write_read", 0, []Method{
+			op(WRITER, MAPP, "a", true, 0, 1),
+			op(WRITER, MAPP, "a", true, 2, 3),
+			op(READER, MAPP, "a", true, 4, 5),
+		}},
+		{"map_read_never_written", 0, []Method{
+			op(WRITER, MAPP, "a", true, 0, 1),
+			op(READER, MAPP, "x", true, 2, 3),
+		}},
+		{"map_read_overlapping_write", 0, []Method{
+			op(READER, MAPP, "a", true, 0, 3),
+			op(WRITER, MAPP, "a", true, 1, 2),
+		}},
+		{"map_read_missing_after_write", 0, []Method{
+			op(WRITER, MAPP, "a", true, 0, 1),
+			op(READER, MAPP, "a", false, 2, 3),
+		}},
+		{"priority_failed_consumer_nonempty", 0, []Method{
+			op(PRODUCER, PRIORITY, "a", true, 0, 1),
+			op(CONSUMER, PRIORITY, "", false, 2, 3),
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := range relaxation {
+				relaxation[i] = tt.k
+			}
+			defer func() {
+				relaxation = [PRIORITY + 1]int{}
+			}()
+
+			v := verifyHistory(tt.methods)
+			got := fmt.Sprintf("correct: %v\nviolations: %v\nrank errors: %v\n", v.correct, v.violations, v.rankErrors)
+			checkGolden(t, tt.name, got)
+		})
+	}
+}
+
+// onThread moves a method to another thread
+func onThread(process int, m Method) Method {
+	m.process = process
+	return m
+}
+
+func TestConditions(t *testing.T) {
+	// a FIFO reorder across two threads, inside one quiescent block
+	reorder := []Method{
+		onThread(0, op(PRODUCER, FIFO, "a", true, 0, 1)),
+		onThread(1, op(PRODUCER, FIFO, "b", true, 2, 3)),
+		onThread(1, op(CONSUMER, FIFO, "b", true, 4, 5)),
+		onThread(0, op(CONSUMER, FIFO, "a", true, 6, 7)),
+		onThread(2, op(PRODUCER, FIFO, "c", true, 0, 10)),
+	}
+	// the same reorder on one thread, split by a quiescent point
+	reorderAfterQuiescence := []Method{
+		onThread(0, op(PRODUCER, FIFO, "a", true, 0, 1)),
+		onThread(0, op(PRODUCER, FIFO, "b", true, 2, 3)),
+		onThread(0, op(CONSUMER, FIFO, "b", true, 4, 5)),
+		onThread(0, op(CONSUMER, FIFO, "a", true, 6, 7)),
+	}
+
+	tests := []struct {
+		name      string
+		condition Condition
+		methods   []Method
+	}{
+		{"linearizability_reorder", LINEARIZABILITY, reorder},
+		{"sequential_reorder", SEQUENTIAL, reorder},
+		{"quiescent_reorder", QUIESCENT, reorder},
+		{"sequential_reorder_one_thread", SEQUENTIAL, reorderAfterQuiescence},
+		{"quiescent_reorder_after_quiescence", QUIESCENT, reorderAfterQuiescence},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			condition = tt.condition
+			defer func() {
+				condition = LINEARIZABILITY
+			}()
+
+			methods := append([]Method(nil), tt.methods...)
+			v := verifyHistory(methods)
+			got := fmt.Sprintf("correct: %v\nviolations: %v\n", v.correct, v.violations)
+			checkGolden(t, tt.name, got)
+		})
+	}
+}
+
+func TestDetectBlocks(t *testing.T) {
+	methods := []Method{
+		op(PRODUCER, FIFO, "a", true, 0, 3),
+		op(PRODUCER, FIFO, "b", true, 1, 2),
+		op(CONSUMER, FIFO, "a", true, 4, 6),
+		op(CONSUMER, FIFO, "b", true, 5, 7),
+		op(PRODUCER, FIFO, "c", true, 9, 10),
+	}
+
+	blocks := detectBlocks(methods)
+	if len(blocks) != 3 {
+		t.Fatalf("got %d blocks, want 3: %+v", len(blocks), blocks)
+	}
+	if blocks[1].start != 4 || blocks[1].finish != 7 {
+		t.Errorf("second block is [%d, %d], want [4, 7]", blocks[1].start, blocks[1].finish)
+	}
+	for i, want := range []int{0, 0, 1, 1, 2} {
+		if methods[i].quiescentPeriod != want {
+			t.Errorf("method %d in block %d, want %d", i, methods[i].quiescentPeriod, want)
+		}
+	}
+}
+
+func TestVerifyBlocks(t *testing.T) {
+	methods := []Method{
+		// block 0: fine
+		op(PRODUCER, FIFO, "a", true, 0, 2),
+		op(PRODUCER, FIFO, "b", true, 1, 3),
+		// block 1: c overtakes a
+		op(PRODUCER, FIFO, "c", true, 4, 5),
+		op(CONSUMER, FIFO, "c", true, 5, 7),
+		op(CONSUMER, FIFO, "a", true, 6, 8),
+		// block 2: fine on its own
+		op(CONSUMER, FIFO, "b", true, 10, 11),
+	}
+
+	v := verifyHistory(methods)
+
+	got := fmt.Sprintf("correct: %v\n", v.correct)
+	for _, b := range v.blocks {
+		got += fmt.Sprintf("block [%d, %d] methods %d correct %v new violations %v\n", b.start, b.finish, b.methods, b.correct, b.violations)
+	}
+	checkGolden(t, "blocks_localized", got)
+}
+
+func TestExtendBlocks(t *testing.T) {
+	// collected in two rounds, the way verify does
+	methods := []Method{
+		op(PRODUCER, FIFO, "a", true, 0, 2),
+		op(PRODUCER, FIFO, "b", true, 1, 3),
+	}
+	items := make([]Item, 3)
+	items[0].setItem("a")
+	items[1].setItem("b")
+	items[2].setItem("c")
+	finalOutcome, finalViolations, rankErrors = true, nil, nil
+	var itStart int
+	var countIterated uint64
+
+	blocks := extendBlocks(methods, 0, make([]Block, 0))
+	verifyBlocks(context.Background(), methods, items, &itStart, &countIterated, blocks, nil)
+
+	collected := len(methods)
+	methods = append(methods,
+		op(PRODUCER, FIFO, "c", true, 4, 5),
+		op(CONSUMER, FIFO, "c", true, 6, 7),
+		op(CONSUMER, FIFO, "b", true, 8, 9),
+	)
+	blocks = extendBlocks(methods, collected, blocks)
+	verifyBlocks(context.Background(), methods, items, &itStart, &countIterated, blocks, nil)
+
+	got := ""
+	for _, b := range blocks {
+		got += fmt.Sprintf("[%d, %d] methods %d correct %v; ", b.start, b.finish, b.methods, b.correct)
+	}
+	// the first block keeps what the first round found, c overtakes a and b
+	if want := "[0, 3] methods 2 correct true; [4, 5] methods 1 correct true; [6, 7] methods 1 correct false; [8, 9] methods 1 correct true; "; got != want {
+		t.Errorf("blocks %s\nwant   %s", got, want)
+	}
+}
+
+func checkGolden(t *testing.T, name string, got string) {
+	t.Helper()
+
+	golden := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+// small enough for the brute-force oracle
+const maxFuzzMethods = 6
+
+// decodeHistory turns arbitrary bytes into a history, four bytes per method
+func decodeHistory(b []byte) []Method {
+	methods := make([]Method, 0)
+	for i := 0; i+4 <= len(b) && len(methods) < 4*maxFuzzMethods; i += 4 {
+		var m Method
+		m.id = len(methods)
+		m.types = Types(b[i] % 4)
+		m.semantics = Semantics((b[i] >> 2) % 5)
+		m.status = b[i]&0x80 == 0
+		m.itemAddrS = fmt.Sprintf("k%d", b[i+1]%4)
+		m.requestAmnt = int(int8(b[i+1]))
+		m.invocation = int64(b[i+2])
+		m.response = m.invocation + int64(b[i+3])
+		methods = append(methods, m)
+	}
+	return methods
+}
+
+// decodeQueueHistory turns arbitrary bytes into a FIFO queue history with
+// unique enqueued keys, three bytes per method
+func decodeQueueHistory(b []byte) []Method {
+	methods := make([]Method, 0)
+	produced := 0
+	for i := 0; i+3 <= len(b) && len(methods) < maxFuzzMethods; i += 3 {
+		var m Method
+		m.id = len(methods)
+		m.semantics = FIFO
+		if b[i]&1 == 0 {
+			m.types = PRODUCER
+			m.status = true
+			m.itemAddrS = fmt.Sprintf("p%d", produced)
+			produced++
+		} else {
+			m.types = CONSUMER
+			m.status = b[i]&2 == 0
+			// p<produced> has not been enqueued (yet)
+			m.itemAddrS = fmt.Sprintf("p%d", int(b[i+1])%(produced+1))
+		}
+		m.invocation = int64(b[i+2] % 32)
+		m.response = m.invocation + int64((b[i]>>2)%8)
+		methods = append(methods, m)
+	}
+	return methods
+}
+
+// linearizable is a brute-force oracle: it tries every order of methods that
+// respects real time against a sequential FIFO queue
+func linearizable(methods []Method) bool {
+	used := make([]bool, len(methods))
+
+	var search func(n int, queue []string) bool
+	search = func(n int, queue []string) bool {
+		if n == len(methods) {
+			return true
+		}
+		for i := range methods {
+			if used[i] {
+				continue
+			}
+			minimal := true
+			for j := range methods {
+				if !used[j] && j != i && precedes(&methods[j], &methods[i]) {
+					minimal = false
+					break
+				}
+			}
+			if !minimal {
+				continue
+			}
+
+			next := queue
+			switch {
+			case methods[i].types == PRODUCER:
+				next = append(queue[:len(queue):len(queue)], methods[i].itemAddrS)
+			case methods[i].status:
+				if len(queue) == 0 || queue[0] != methods[i].itemAddrS {
+					continue
+				}
+				next = queue[1:]
+			default:
+				if len(queue) != 0 {
+					continue
+				}
+			}
+
+			used[i] = true
+			if search(n+1, next) {
+				return true
+			}
+			used[i] = false
+		}
+		return false
+	}
+	return search(0, nil)
+}
+
+func FuzzVerifyCheckpoint(f *testing.F) {
+	f.Add([]byte{0, 0, 0, 1, 1, 0, 2, 1})
+	f.Add([]byte{0x80, 1, 0, 0, 0x81, 1, 0, 0, 1, 1, 3, 3})
+	f.Add([]byte{0, 0, 0, 1, 0, 1, 2, 1, 1, 1, 4, 1, 0x81, 2, 6, 1})
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		methods := decodeHistory(b)
+
+		// must not panic on any history
+		verifyHistory(methods)
+	})
+}
+
+func FuzzLinearizability(f *testing.F) {
+	f.Add([]byte{0, 0, 0, 1, 0, 4})                    // enqueue, dequeue
+	f.Add([]byte{0, 0, 0, 0, 0, 4, 1, 1, 8, 1, 0, 12}) // FIFO reorder
+	f.Add([]byte{0, 0, 0, 3, 0, 4})                    // failed dequeue on non-empty queue
+	f.Add([]byte{1, 0, 0})                             // dequeue of never enqueued key
+	f.Add([]byte{0x1c, 0, 0, 0x1c, 0, 1, 0x1d, 1, 2})  // overlapping
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		methods := decodeQueueHistory(b)
+
+		// the fractional algorithm is sound on concurrent histories
+		if want := linearizable(methods); want && !verifyHistory(methods).correct {
+			t.Fatalf("verifier rejects linearizable history %+v", methods)
+		}
+
+		// and exact once no two methods overlap
+		for i := range methods {
+			methods[i].invocation = int64(2 * i)
+			methods[i].response = int64(2*i + 1)
+		}
+		if got, want := verifyHistory(methods).correct, linearizable(methods); got != want {
+			t.Fatalf("verifier says %v, oracle says %v for sequential %+v", got, want, methods)
+		}
+	})
+}
+
+func TestWorkTiming(t *testing.T) {
+	savedLists, savedBalances, savedSenders, savedTxns := threadLists.items, balances, allSenders, transactions
+	defer func() {
+		threadLists.items, balances, allSenders, transactions = savedLists, savedBalances, savedSenders, savedTxns
+		methodTime, overheadTime = [numThreads]int64{}, [numThreads]int64{}
+		threadListsSize[0].Store(0)
+		running, arrived = 0, 0
+		txnCtr.val = 0
+	}()
+
+	threadLists.items = []interface{}{[]Method{}}
+	balances = map[string]int{"a": 10}
+	allSenders = map[string]int{"a": 0}
+	transactions = []TransactionData{{addrSender: "a", addrReceiver: "b", amount: 3}}
+	txnCtr.val = 0
+	numTxns = 1
+	running = 1
+	methodTime, overheadTime = [numThreads]int64{}, [numThreads]int64{}
+	start = time.Now()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	work(context.Background(), 0, &wg)
+
+	methods := threadLists.items[0].([]Method)
+	if len(methods) != 2 || !methods[0].status {
+		t.Fatalf("recorded %+v", methods)
+	}
+	// method time is the operation itself, in nanoseconds
+	if want := methods[0].response - methods[0].invocation; methodTime[0] != want {
+		t.Errorf("method time %d, operation took %d", methodTime[0], want)
+	}
+	if overheadTime[0] <= 0 {
+		t.Errorf("overhead time %d", overheadTime[0])
+	}
+	if got := maxThreadTime([]int64{2e9, 5e8}); got != 2 {
+		t.Errorf("max thread time %v", got)
+	}
+}
+
+func TestVerifyStopped(t *testing.T) {
+	// b is consumed in the last block without being produced
+	history := func() []Method {
+		return []Method{
+			op(PRODUCER, FIFO, "a", true, 0, 1),
+			op(CONSUMER, FIFO, "a", true, 2, 3),
+			op(CONSUMER, FIFO, "b", true, 4, 5),
+		}
+	}
+	if verifyHistory(history()).correct {
+		t.Fatal("history verified correct")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if v := verifyHistoryContext(ctx, history()); !v.correct || v.verified != 0 || v.stopped != context.Canceled {
+		t.Errorf("cancelled verification %+v", v)
+	}
+	ctx, cancel = context.WithDeadline(context.Background(), time.Now())
+	defer cancel()
+	if v := verifyHistoryContext(ctx, history()); v.stopped != context.DeadlineExceeded {
+		t.Errorf("verification past its deadline %+v", v)
+	}
+
+	// cancelled once the first two blocks are verified
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	methods := history()
+	items := make([]Item, 2)
+	items[0].setItem("a")
+	items[1].setItem("b")
+	finalOutcome, finalViolations, rankErrors = true, nil, nil
+	var itStart int
+	var countIterated uint64
+	verified, err := verifyBlocks(ctx, methods, items, &itStart, &countIterated, detectBlocks(methods), func(n int) {
+		if n == 2 {
+			cancel()
+		}
+	})
+	if verified != 2 || err != context.Canceled || !finalOutcome {
+		t.Errorf("verified %d, %v, correct %v", verified, err, finalOutcome)
+	}
+
+	// a thread that never arrives at the barrier
+	running = 2
+	defer func() { running, arrived = 0, 0 }()
+	if err := wait(ctx); err != context.Canceled {
+		t.Errorf("barrier returned %v", err)
+	}
+}
+
+func TestCollectorWaits(t *testing.T) {
+	savedLists := threadLists.items
+	defer func() {
+		threadLists.items = savedLists
+		for i := range threadListsSize {
+			threadListsSize[i].Store(0)
+		}
+		running = 0
+	}()
+	reset := func(threads int) {
+		threadLists.items = make([]interface{}, numThreads)
+		for i := range threadLists.items {
+			threadLists.items[i] = []Method{}
+			threadListsSize[i].Store(0)
+		}
+		running = threads
+		start = time.Now()
+	}
+
+	// the collector sleeps until the threads record their methods and finish
+	reset(2)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go verify(context.Background(), &wg)
+	record(0, op(PRODUCER, FIFO, "a", true, 0, 1))
+	finishThread()
+	record(1, op(CONSUMER, FIFO, "a", true, 2, 3))
+	finishThread()
+	wg.Wait()
+	if len(recorded) != 2 || verified != 2 || stopped != nil || !finalOutcome {
+		t.Errorf("collected %d, verified %d, stopped %v, correct %v", len(recorded), verified, stopped, finalOutcome)
+	}
+
+	// and gives up on a thread that never finishes once ctx is done
+	reset(1)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	wg.Add(1)
+	go verify(ctx, &wg)
+	record(0, op(PRODUCER, FIFO, "b", true, 0, 1))
+	wg.Wait()
+	if stopped != context.DeadlineExceeded {
+		t.Errorf("collector stopped with %v", stopped)
+	}
+}
+
+func TestParseRelaxation(t *testing.T) {
+	k, err := parseRelaxation("fifo=2, priority=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [PRIORITY + 1]int{FIFO: 2, PRIORITY: 1}; k != want {
+		t.Errorf("relaxation %v, want %v", k, want)
+	}
+	if s := relaxationString(k); s != "fifo=2,priority=1" {
+		t.Errorf("relaxationString = %q", s)
+	}
+
+	for _, spec := range []string{"fifo", "fifo=-1", "set=1", "queue=1"} {
+		if _, err := parseRelaxation(spec); err == nil {
+			t.Errorf("%q: no error", spec)
+		}
 	}
 }
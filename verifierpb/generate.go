@@ -0,0 +1,5 @@
+// Package verifierpb holds the protobuf schema of the verifier's gRPC
+// service and the code generated from it.
+package verifierpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative verifier.proto
@@ -0,0 +1,918 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.29.3
+// source: verifier.proto
+
+package verifierpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Semantics and Types keep the numbering of the Go enums.
+type Semantics int32
+
+const (
+	Semantics_FIFO     Semantics = 0
+	Semantics_LIFO     Semantics = 1
+	Semantics_SET      Semantics = 2
+	Semantics_MAPP     Semantics = 3
+	Semantics_PRIORITY Semantics = 4
+)
+
+// Enum value maps for Semantics.
+var (
+	Semantics_name = map[int32]string{
+		0: "FIFO",
+		1: "LIFO",
+		2: "SET",
+		3: "MAPP",
+		4: "PRIORITY",
+	}
+	Semantics_value = map[string]int32{
+		"FIFO":     0,
+		"LIFO":     1,
+		"SET":      2,
+		"MAPP":     3,
+		"PRIORITY": 4,
+	}
+)
+
+func (x Semantics) Enum() *Semantics {
+	p := new(Semantics)
+	*p = x
+	return p
+}
+
+func (x Semantics) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Semantics) Descriptor() protoreflect.EnumDescriptor {
+	return file_verifier_proto_enumTypes[0].Descriptor()
+}
+
+func (Semantics) Type() protoreflect.EnumType {
+	return &file_verifier_proto_enumTypes[0]
+}
+
+func (x Semantics) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Semantics.Descriptor instead.
+func (Semantics) EnumDescriptor() ([]byte, []int) {
+	return file_verifier_proto_rawDescGZIP(), []int{0}
+}
+
+type Types int32
+
+const (
+	Types_PRODUCER Types = 0
+	Types_CONSUMER Types = 1
+	Types_READER   Types = 2
+	Types_WRITER   Types = 3
+)
+
+// Enum value maps for Types.
+var (
+	Types_name = map[int32]string{
+		0: "PRODUCER",
+		1: "CONSUMER",
+		2: "READER",
+		3: "WRITER",
+	}
+	Types_value = map[string]int32{
+		"PRODUCER": 0,
+		"CONSUMER": 1,
+		"READER":   2,
+		"WRITER":   3,
+	}
+)
+
+func (x Types) Enum() *Types {
+	p := new(Types)
+	*p = x
+	return p
+}
+
+func (x Types) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Types) Descriptor() protoreflect.EnumDescriptor {
+	return file_verifier_proto_enumTypes[1].Descriptor()
+}
+
+func (Types) Type() protoreflect.EnumType {
+	return &file_verifier_proto_enumTypes[1]
+}
+
+func (x Types) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Types.Descriptor instead.
+func (Types) EnumDescriptor() ([]byte, []int) {
+	return file_verifier_proto_rawDescGZIP(), []int{1}
+}
+
+type Condition int32
+
+const (
+	Condition_LINEARIZABILITY Condition = 0
+	Condition_SEQUENTIAL      Condition = 1
+	Condition_QUIESCENT       Condition = 2
+)
+
+// Enum value maps for Condition.
+var (
+	Condition_name = map[int32]string{
+		0: "LINEARIZABILITY",
+		1: "SEQUENTIAL",
+		2: "QUIESCENT",
+	}
+	Condition_value = map[string]int32{
+		"LINEARIZABILITY": 0,
+		"SEQUENTIAL":      1,
+		"QUIESCENT":       2,
+	}
+)
+
+func (x Condition) Enum() *Condition {
+	p := new(Condition)
+	*p = x
+	return p
+}
+
+func (x Condition) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Condition) Descriptor() protoreflect.EnumDescriptor {
+	return file_verifier_proto_enumTypes[2].Descriptor()
+}
+
+func (Condition) Type() protoreflect.EnumType {
+	return &file_verifier_proto_enumTypes[2]
+}
+
+func (x Condition) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Condition.Descriptor instead.
+func (Condition) EnumDescriptor() ([]byte, []int) {
+	return file_verifier_proto_rawDescGZIP(), []int{2}
+}
+
+type Method struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Thread        int32                  `protobuf:"varint,1,opt,name=thread,proto3" json:"thread,omitempty"`
+	Type          Types                  `protobuf:"varint,2,opt,name=type,proto3,enum=verifier.Types" json:"type,omitempty"`
+	Semantics     Semantics              `protobuf:"varint,3,opt,name=semantics,proto3,enum=verifier.Semantics" json:"semantics,omitempty"`
+	Key           string                 `protobuf:"bytes,4,opt,name=key,proto3" json:"key,omitempty"`                // sender account address
+	Receiver      string                 `protobuf:"bytes,5,opt,name=receiver,proto3" json:"receiver,omitempty"`      // receiver account address
+	Value         int64                  `protobuf:"varint,6,opt,name=value,proto3" json:"value,omitempty"`           // account balance
+	Invocation    int64                  `protobuf:"varint,7,opt,name=invocation,proto3" json:"invocation,omitempty"` // nanoseconds since start
+	Response      int64                  `protobuf:"varint,8,opt,name=response,proto3" json:"response,omitempty"`     // nanoseconds since start
+	Status        bool                   `protobuf:"varint,9,opt,name=status,proto3" json:"status,omitempty"`
+	RequestAmount int64                  `protobuf:"varint,10,opt,name=request_amount,json=requestAmount,proto3" json:"request_amount,omitempty"`
+	TxnCtr        int32                  `protobuf:"varint,11,opt,name=txn_ctr,json=txnCtr,proto3" json:"txn_ctr,omitempty"`
+	Nonce         int64                  `protobuf:"varint,12,opt,name=nonce,proto3" json:"nonce,omitempty"` // sender's sequence number, transactions only
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Method) Reset() {
+	*x = Method{}
+	mi := &file_verifier_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Method) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Method) ProtoMessage() {}
+
+func (x *Method) ProtoReflect() protoreflect.Message {
+	mi := &file_verifier_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Method.ProtoReflect.Descriptor instead.
+func (*Method) Descriptor() ([]byte, []int) {
+	return file_verifier_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Method) GetThread() int32 {
+	if x != nil {
+		return x.Thread
+	}
+	return 0
+}
+
+func (x *Method) GetType() Types {
+	if x != nil {
+		return x.Type
+	}
+	return Types_PRODUCER
+}
+
+func (x *Method) GetSemantics() Semantics {
+	if x != nil {
+		return x.Semantics
+	}
+	return Semantics_FIFO
+}
+
+func (x *Method) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *Method) GetReceiver() string {
+	if x != nil {
+		return x.Receiver
+	}
+	return ""
+}
+
+func (x *Method) GetValue() int64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+func (x *Method) GetInvocation() int64 {
+	if x != nil {
+		return x.Invocation
+	}
+	return 0
+}
+
+func (x *Method) GetResponse() int64 {
+	if x != nil {
+		return x.Response
+	}
+	return 0
+}
+
+func (x *Method) GetStatus() bool {
+	if x != nil {
+		return x.Status
+	}
+	return false
+}
+
+func (x *Method) GetRequestAmount() int64 {
+	if x != nil {
+		return x.RequestAmount
+	}
+	return 0
+}
+
+func (x *Method) GetTxnCtr() int32 {
+	if x != nil {
+		return x.TxnCtr
+	}
+	return 0
+}
+
+func (x *Method) GetNonce() int64 {
+	if x != nil {
+		return x.Nonce
+	}
+	return 0
+}
+
+type TransactionData struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	AddrSender      string                 `protobuf:"bytes,1,opt,name=addr_sender,json=addrSender,proto3" json:"addr_sender,omitempty"`
+	AddrReceiver    string                 `protobuf:"bytes,2,opt,name=addr_receiver,json=addrReceiver,proto3" json:"addr_receiver,omitempty"`
+	BalanceSender   int64                  `protobuf:"varint,3,opt,name=balance_sender,json=balanceSender,proto3" json:"balance_sender,omitempty"`
+	BalanceReceiver int64                  `protobuf:"varint,4,opt,name=balance_receiver,json=balanceReceiver,proto3" json:"balance_receiver,omitempty"`
+	Amount          int64                  `protobuf:"varint,5,opt,name=amount,proto3" json:"amount,omitempty"`
+	TId             int32                  `protobuf:"varint,6,opt,name=t_id,json=tId,proto3" json:"t_id,omitempty"`
+	Nonce           int64                  `protobuf:"varint,7,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *TransactionData) Reset() {
+	*x = TransactionData{}
+	mi := &file_verifier_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransactionData) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransactionData) ProtoMessage() {}
+
+func (x *TransactionData) ProtoReflect() protoreflect.Message {
+	mi := &file_verifier_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransactionData.ProtoReflect.Descriptor instead.
+func (*TransactionData) Descriptor() ([]byte, []int) {
+	return file_verifier_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *TransactionData) GetAddrSender() string {
+	if x != nil {
+		return x.AddrSender
+	}
+	return ""
+}
+
+func (x *TransactionData) GetAddrReceiver() string {
+	if x != nil {
+		return x.AddrReceiver
+	}
+	return ""
+}
+
+func (x *TransactionData) GetBalanceSender() int64 {
+	if x != nil {
+		return x.BalanceSender
+	}
+	return 0
+}
+
+func (x *TransactionData) GetBalanceReceiver() int64 {
+	if x != nil {
+		return x.BalanceReceiver
+	}
+	return 0
+}
+
+func (x *TransactionData) GetAmount() int64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *TransactionData) GetTId() int32 {
+	if x != nil {
+		return x.TId
+	}
+	return 0
+}
+
+func (x *TransactionData) GetNonce() int64 {
+	if x != nil {
+		return x.Nonce
+	}
+	return 0
+}
+
+// A transaction as the workers run it: a producer and a consumer on the
+// sender's account.
+type Transaction struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Data          *TransactionData       `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	Thread        int32                  `protobuf:"varint,2,opt,name=thread,proto3" json:"thread,omitempty"`
+	Invocation    int64                  `protobuf:"varint,3,opt,name=invocation,proto3" json:"invocation,omitempty"`
+	Response      int64                  `protobuf:"varint,4,opt,name=response,proto3" json:"response,omitempty"`
+	Status        bool                   `protobuf:"varint,5,opt,name=status,proto3" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Transaction) Reset() {
+	*x = Transaction{}
+	mi := &file_verifier_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Transaction) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Transaction) ProtoMessage() {}
+
+func (x *Transaction) ProtoReflect() protoreflect.Message {
+	mi := &file_verifier_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Transaction.ProtoReflect.Descriptor instead.
+func (*Transaction) Descriptor() ([]byte, []int) {
+	return file_verifier_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Transaction) GetData() *TransactionData {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *Transaction) GetThread() int32 {
+	if x != nil {
+		return x.Thread
+	}
+	return 0
+}
+
+func (x *Transaction) GetInvocation() int64 {
+	if x != nil {
+		return x.Invocation
+	}
+	return 0
+}
+
+func (x *Transaction) GetResponse() int64 {
+	if x != nil {
+		return x.Response
+	}
+	return 0
+}
+
+func (x *Transaction) GetStatus() bool {
+	if x != nil {
+		return x.Status
+	}
+	return false
+}
+
+// The rank error a consumer of the semantics may make, k-FIFO, k-LIFO or
+// k-priority; semantics without one are strict.
+type Relaxation struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Semantics     Semantics              `protobuf:"varint,1,opt,name=semantics,proto3,enum=verifier.Semantics" json:"semantics,omitempty"`
+	K             int32                  `protobuf:"varint,2,opt,name=k,proto3" json:"k,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Relaxation) Reset() {
+	*x = Relaxation{}
+	mi := &file_verifier_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Relaxation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Relaxation) ProtoMessage() {}
+
+func (x *Relaxation) ProtoReflect() protoreflect.Message {
+	mi := &file_verifier_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Relaxation.ProtoReflect.Descriptor instead.
+func (*Relaxation) Descriptor() ([]byte, []int) {
+	return file_verifier_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Relaxation) GetSemantics() Semantics {
+	if x != nil {
+		return x.Semantics
+	}
+	return Semantics_FIFO
+}
+
+func (x *Relaxation) GetK() int32 {
+	if x != nil {
+		return x.K
+	}
+	return 0
+}
+
+type RecordRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Only read on the first message of a stream, which opens the session.
+	Condition    Condition      `protobuf:"varint,1,opt,name=condition,proto3,enum=verifier.Condition" json:"condition,omitempty"`
+	Methods      []*Method      `protobuf:"bytes,2,rep,name=methods,proto3" json:"methods,omitempty"`
+	Transactions []*Transaction `protobuf:"bytes,3,rep,name=transactions,proto3" json:"transactions,omitempty"`
+	// Verify the rest of the history; the stream ending does the same.
+	Close bool `protobuf:"varint,4,opt,name=close,proto3" json:"close,omitempty"`
+	// Only read on the first message, like condition.
+	Relaxation    []*Relaxation `protobuf:"bytes,5,rep,name=relaxation,proto3" json:"relaxation,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RecordRequest) Reset() {
+	*x = RecordRequest{}
+	mi := &file_verifier_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RecordRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RecordRequest) ProtoMessage() {}
+
+func (x *RecordRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_verifier_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RecordRequest.ProtoReflect.Descriptor instead.
+func (*RecordRequest) Descriptor() ([]byte, []int) {
+	return file_verifier_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *RecordRequest) GetCondition() Condition {
+	if x != nil {
+		return x.Condition
+	}
+	return Condition_LINEARIZABILITY
+}
+
+func (x *RecordRequest) GetMethods() []*Method {
+	if x != nil {
+		return x.Methods
+	}
+	return nil
+}
+
+func (x *RecordRequest) GetTransactions() []*Transaction {
+	if x != nil {
+		return x.Transactions
+	}
+	return nil
+}
+
+func (x *RecordRequest) GetClose() bool {
+	if x != nil {
+		return x.Close
+	}
+	return false
+}
+
+func (x *RecordRequest) GetRelaxation() []*Relaxation {
+	if x != nil {
+		return x.Relaxation
+	}
+	return nil
+}
+
+type Verdict struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Session    string                 `protobuf:"bytes,1,opt,name=session,proto3" json:"session,omitempty"`
+	Correct    bool                   `protobuf:"varint,2,opt,name=correct,proto3" json:"correct,omitempty"`
+	Closed     bool                   `protobuf:"varint,3,opt,name=closed,proto3" json:"closed,omitempty"`
+	Methods    int64                  `protobuf:"varint,4,opt,name=methods,proto3" json:"methods,omitempty"`
+	Verified   uint64                 `protobuf:"varint,5,opt,name=verified,proto3" json:"verified,omitempty"`
+	Violations []string               `protobuf:"bytes,6,rep,name=violations,proto3" json:"violations,omitempty"`
+	RankErrors []int64                `protobuf:"varint,7,rep,packed,name=rank_errors,json=rankErrors,proto3" json:"rank_errors,omitempty"`
+	// Every method on a violating item.
+	Counterexample []*Method `protobuf:"bytes,8,rep,name=counterexample,proto3" json:"counterexample,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Verdict) Reset() {
+	*x = Verdict{}
+	mi := &file_verifier_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Verdict) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Verdict) ProtoMessage() {}
+
+func (x *Verdict) ProtoReflect() protoreflect.Message {
+	mi := &file_verifier_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Verdict.ProtoReflect.Descriptor instead.
+func (*Verdict) Descriptor() ([]byte, []int) {
+	return file_verifier_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Verdict) GetSession() string {
+	if x != nil {
+		return x.Session
+	}
+	return ""
+}
+
+func (x *Verdict) GetCorrect() bool {
+	if x != nil {
+		return x.Correct
+	}
+	return false
+}
+
+func (x *Verdict) GetClosed() bool {
+	if x != nil {
+		return x.Closed
+	}
+	return false
+}
+
+func (x *Verdict) GetMethods() int64 {
+	if x != nil {
+		return x.Methods
+	}
+	return 0
+}
+
+func (x *Verdict) GetVerified() uint64 {
+	if x != nil {
+		return x.Verified
+	}
+	return 0
+}
+
+func (x *Verdict) GetViolations() []string {
+	if x != nil {
+		return x.Violations
+	}
+	return nil
+}
+
+func (x *Verdict) GetRankErrors() []int64 {
+	if x != nil {
+		return x.RankErrors
+	}
+	return nil
+}
+
+func (x *Verdict) GetCounterexample() []*Method {
+	if x != nil {
+		return x.Counterexample
+	}
+	return nil
+}
+
+type GetVerdictRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Session       string                 `protobuf:"bytes,1,opt,name=session,proto3" json:"session,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetVerdictRequest) Reset() {
+	*x = GetVerdictRequest{}
+	mi := &file_verifier_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetVerdictRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetVerdictRequest) ProtoMessage() {}
+
+func (x *GetVerdictRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_verifier_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetVerdictRequest.ProtoReflect.Descriptor instead.
+func (*GetVerdictRequest) Descriptor() ([]byte, []int) {
+	return file_verifier_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetVerdictRequest) GetSession() string {
+	if x != nil {
+		return x.Session
+	}
+	return ""
+}
+
+var File_verifier_proto protoreflect.FileDescriptor
+
+const file_verifier_proto_rawDesc = "" +
+	"\n" +
+	"\x0everifier.proto\x12\bverifier\"\xe6\x02\n" +
+	"\x06Method\x12\x16\n" +
+	"\x06thread\x18\x01 \x01(\x05R\x06thread\x12#\n" +
+	"\x04type\x18\x02 \x01(\x0e2\x0f.verifier.TypesR\x04type\x121\n" +
+	"\tsemantics\x18\x03 \x01(\x0e2\x13.verifier.SemanticsR\tsemantics\x12\x10\n" +
+	"\x03key\x18\x04 \x01(\tR\x03key\x12\x1a\n" +
+	"\breceiver\x18\x05 \x01(\tR\breceiver\x12\x14\n" +
+	"\x05value\x18\x06 \x01(\x03R\x05value\x12\x1e\n" +
+	"\n" +
+	"invocation\x18\a \x01(\x03R\n" +
+	"invocation\x12\x1a\n" +
+	"\bresponse\x18\b \x01(\x03R\bresponse\x12\x16\n" +
+	"\x06status\x18\t \x01(\bR\x06status\x12%\n" +
+	"\x0erequest_amount\x18\n" +
+	" \x01(\x03R\rrequestAmount\x12\x17\n" +
+	"\atxn_ctr\x18\v \x01(\x05R\x06txnCtr\x12\x14\n" +
+	"\x05nonce\x18\f \x01(\x03R\x05nonce\"\xea\x01\n" +
+	"\x0fTransactionData\x12\x1f\n" +
+	"\vaddr_sender\x18\x01 \x01(\tR\n" +
+	"addrSender\x12#\n" +
+	"\raddr_receiver\x18\x02 \x01(\tR\faddrReceiver\x12%\n" +
+	"\x0ebalance_sender\x18\x03 \x01(\x03R\rbalanceSender\x12)\n" +
+	"\x10balance_receiver\x18\x04 \x01(\x03R\x0fbalanceReceiver\x12\x16\n" +
+	"\x06amount\x18\x05 \x01(\x03R\x06amount\x12\x11\n" +
+	"\x04t_id\x18\x06 \x01(\x05R\x03tId\x12\x14\n" +
+	"\x05nonce\x18\a \x01(\x03R\x05nonce\"\xa8\x01\n" +
+	"\vTransaction\x12-\n" +
+	"\x04data\x18\x01 \x01(\v2\x19.verifier.TransactionDataR\x04data\x12\x16\n" +
+	"\x06thread\x18\x02 \x01(\x05R\x06thread\x12\x1e\n" +
+	"\n" +
+	"invocation\x18\x03 \x01(\x03R\n" +
+	"invocation\x12\x1a\n" +
+	"\bresponse\x18\x04 \x01(\x03R\bresponse\x12\x16\n" +
+	"\x06status\x18\x05 \x01(\bR\x06status\"M\n" +
+	"\n" +
+	"Relaxation\x121\n" +
+	"\tsemantics\x18\x01 \x01(\x0e2\x13.verifier.SemanticsR\tsemantics\x12\f\n" +
+	"\x01k\x18\x02 \x01(\x05R\x01k\"\xf5\x01\n" +
+	"\rRecordRequest\x121\n" +
+	"\tcondition\x18\x01 \x01(\x0e2\x13.verifier.ConditionR\tcondition\x12*\n" +
+	"\amethods\x18\x02 \x03(\v2\x10.verifier.MethodR\amethods\x129\n" +
+	"\ftransactions\x18\x03 \x03(\v2\x15.verifier.TransactionR\ftransactions\x12\x14\n" +
+	"\x05close\x18\x04 \x01(\bR\x05close\x124\n" +
+	"\n" +
+	"relaxation\x18\x05 \x03(\v2\x14.verifier.RelaxationR\n" +
+	"relaxation\"\x86\x02\n" +
+	"\aVerdict\x12\x18\n" +
+	"\asession\x18\x01 \x01(\tR\asession\x12\x18\n" +
+	"\acorrect\x18\x02 \x01(\bR\acorrect\x12\x16\n" +
+	"\x06closed\x18\x03 \x01(\bR\x06closed\x12\x18\n" +
+	"\amethods\x18\x04 \x01(\x03R\amethods\x12\x1a\n" +
+	"\bverified\x18\x05 \x01(\x04R\bverified\x12\x1e\n" +
+	"\n" +
+	"violations\x18\x06 \x03(\tR\n" +
+	"violations\x12\x1f\n" +
+	"\vrank_errors\x18\a \x03(\x03R\n" +
+	"rankErrors\x128\n" +
+	"\x0ecounterexample\x18\b \x03(\v2\x10.verifier.MethodR\x0ecounterexample\"-\n" +
+	"\x11GetVerdictRequest\x12\x18\n" +
+	"\asession\x18\x01 \x01(\tR\asession*@\n" +
+	"\tSemantics\x12\b\n" +
+	"\x04FIFO\x10\x00\x12\b\n" +
+	"\x04LIFO\x10\x01\x12\a\n" +
+	"\x03SET\x10\x02\x12\b\n" +
+	"\x04MAPP\x10\x03\x12\f\n" +
+	"\bPRIORITY\x10\x04*;\n" +
+	"\x05Types\x12\f\n" +
+	"\bPRODUCER\x10\x00\x12\f\n" +
+	"\bCONSUMER\x10\x01\x12\n" +
+	"\n" +
+	"\x06READER\x10\x02\x12\n" +
+	"\n" +
+	"\x06WRITER\x10\x03*?\n" +
+	"\tCondition\x12\x13\n" +
+	"\x0fLINEARIZABILITY\x10\x00\x12\x0e\n" +
+	"\n" +
+	"SEQUENTIAL\x10\x01\x12\r\n" +
+	"\tQUIESCENT\x10\x022\x82\x01\n" +
+	"\bVerifier\x128\n" +
+	"\x06Record\x12\x17.verifier.RecordRequest\x1a\x11.verifier.Verdict(\x010\x01\x12<\n" +
+	"\n" +
+	"GetVerdict\x12\x1b.verifier.GetVerdictRequest\x1a\x11.verifier.VerdictB*Z(github.com/servolino/verifier/verifierpbb\x06proto3"
+
+var (
+	file_verifier_proto_rawDescOnce sync.Once
+	file_verifier_proto_rawDescData []byte
+)
+
+func file_verifier_proto_rawDescGZIP() []byte {
+	file_verifier_proto_rawDescOnce.Do(func() {
+		file_verifier_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_verifier_proto_rawDesc), len(file_verifier_proto_rawDesc)))
+	})
+	return file_verifier_proto_rawDescData
+}
+
+var file_verifier_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_verifier_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_verifier_proto_goTypes = []any{
+	(Semantics)(0),            // 0: verifier.Semantics
+	(Types)(0),                // 1: verifier.Types
+	(Condition)(0),            // 2: verifier.Condition
+	(*Method)(nil),            // 3: verifier.Method
+	(*TransactionData)(nil),   // 4: verifier.TransactionData
+	(*Transaction)(nil),       // 5: verifier.Transaction
+	(*Relaxation)(nil),        // 6: verifier.Relaxation
+	(*RecordRequest)(nil),     // 7: verifier.RecordRequest
+	(*Verdict)(nil),           // 8: verifier.Verdict
+	(*GetVerdictRequest)(nil), // 9: verifier.GetVerdictRequest
+}
+var file_verifier_proto_depIdxs = []int32{
+	1,  // 0: verifier.Method.type:type_name -> verifier.Types
+	0,  // 1: verifier.Method.semantics:type_name -> verifier.Semantics
+	4,  // 2: verifier.Transaction.data:type_name -> verifier.TransactionData
+	0,  // 3: verifier.Relaxation.semantics:type_name -> verifier.Semantics
+	2,  // 4: verifier.RecordRequest.condition:type_name -> verifier.Condition
+	3,  // 5: verifier.RecordRequest.methods:type_name -> verifier.Method
+	5,  // 6: verifier.RecordRequest.transactions:type_name -> verifier.Transaction
+	6,  // 7: verifier.RecordRequest.relaxation:type_name -> verifier.Relaxation
+	3,  // 8: verifier.Verdict.counterexample:type_name -> verifier.Method
+	7,  // 9: verifier.Verifier.Record:input_type -> verifier.RecordRequest
+	9,  // 10: verifier.Verifier.GetVerdict:input_type -> verifier.GetVerdictRequest
+	8,  // 11: verifier.Verifier.Record:output_type -> verifier.Verdict
+	8,  // 12: verifier.Verifier.GetVerdict:output_type -> verifier.Verdict
+	11, // [11:13] is the sub-list for method output_type
+	9,  // [9:11] is the sub-list for method input_type
+	9,  // [9:9] is the sub-list for extension type_name
+	9,  // [9:9] is the sub-list for extension extendee
+	0,  // [0:9] is the sub-list for field type_name
+}
+
+func init() { file_verifier_proto_init() }
+func file_verifier_proto_init() {
+	if File_verifier_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_verifier_proto_rawDesc), len(file_verifier_proto_rawDesc)),
+			NumEnums:      3,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_verifier_proto_goTypes,
+		DependencyIndexes: file_verifier_proto_depIdxs,
+		EnumInfos:         file_verifier_proto_enumTypes,
+		MessageInfos:      file_verifier_proto_msgTypes,
+	}.Build()
+	File_verifier_proto = out.File
+	file_verifier_proto_goTypes = nil
+	file_verifier_proto_depIdxs = nil
+}
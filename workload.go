@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// distribution draws integers in [lo, hi]
+type distribution struct {
+	kind    string // fixed, uniform, zipf or hotspot
+	lo, hi  int
+	s       float64 // zipf exponent, above 1
+	hot     float64 // hotspot: fraction of the range that is hot
+	hotProb float64 // hotspot: probability of drawing from the hot part
+}
+
+// parseDistribution reads fixed:N, uniform:LO:HI, zipf:S or hotspot:HOT:PROB.
+// Distributions without bounds of their own range over [lo, hi], lower
+// values being the likelier under zipf and the hot ones under hotspot.
+func parseDistribution(spec string, lo, hi int) (distribution, error) {
+	parts := strings.Split(spec, ":")
+	d := distribution{kind: parts[0], lo: lo, hi: hi}
+	floats := func(n int) ([]float64, error) {
+		if len(parts) != n+1 {
+			return nil, fmt.Errorf("distribution %q wants %d parameters", spec, n)
+		}
+		fs := make([]float64, n)
+		for i := range fs {
+			f, err := strconv.ParseFloat(parts[i+1], 64)
+			if err != nil {
+				return nil, fmt.Errorf("distribution %q: %v", spec, err)
+			}
+			fs[i] = f
+		}
+		return fs, nil
+	}
+
+	switch d.kind {
+	case "fixed":
+		fs, err := floats(1)
+		if err != nil {
+			return d, err
+		}
+		d.lo, d.hi = int(fs[0]), int(fs[0])
+	case "uniform":
+		if len(parts) == 1 {
+			break
+		}
+		fs, err := floats(2)
+		if err != nil {
+			return d, err
+		}
+		d.lo, d.hi = int(fs[0]), int(fs[1])
+	case "zipf":
+		fs, err := floats(1)
+		if err != nil {
+			return d, err
+		}
+		if d.s = fs[0]; d.s <= 1 {
+			return d, fmt.Errorf("distribution %q: zipf exponent must be above 1", spec)
+		}
+	case "hotspot":
+		fs, err := floats(2)
+		if err != nil {
+			return d, err
+		}
+		d.hot, d.hotProb = fs[0], fs[1]
+		if d.hot <= 0 || d.hot > 1 || d.hotProb < 0 || d.hotProb > 1 {
+			return d, fmt.Errorf("distribution %q: fraction and probability must be in (0, 1]", spec)
+		}
+	default:
+		return d, fmt.Errorf("unknown distribution %q, want fixed, uniform, zipf or hotspot", spec)
+	}
+	if d.hi < d.lo {
+		return d, fmt.Errorf("distribution %q is empty", spec)
+	}
+	return d, nil
+}
+
+// sampler draws from d with rng
+func (d distribution) sampler(rng *rand.Rand) func() int {
+	n := d.hi - d.lo + 1
+	switch d.kind {
+	case "zipf":
+		if n == 1 {
+			break
+		}
+		z := rand.NewZipf(rng, d.s, 1, uint64(n-1))
+		return func() int { return d.lo + int(z.Uint64()) }
+	case "hotspot":
+		hot := int(float64(n) * d.hot)
+		if hot < 1 {
+			hot = 1
+		}
+		return func() int {
+			if hot == n || rng.Float64() < d.hotProb {
+				return d.lo + rng.Intn(hot)
+			}
+			return d.lo + hot + rng.Intn(n-hot)
+		}
+	}
+	return func() int { return d.lo + rng.Intn(n) }
+}
+
+// workloadConfig is what main's generator builds the transactions from
+type workloadConfig struct {
+	transactions int
+	accounts     int
+	keys         string // distribution of the accounts taking part
+	amounts      string // distribution of transfer amounts and written values
+	transfer     int    // weights of the operation mix
+	read         int
+	write        int
+	seed         int64
+}
+
+// parseMix reads weights like transfer=80,read=15,write=5 into c
+func (c *workloadConfig) parseMix(spec string) error {
+	c.transfer, c.read, c.write = 0, 0, 0
+	for _, part := range strings.Split(spec, ",") {
+		name, weight, ok := strings.Cut(strings.TrimSpace(part), "=")
+		w, err := strconv.Atoi(weight)
+		if !ok || err != nil || w < 0 {
+			return fmt.Errorf("operation mix %q: want name=weight", part)
+		}
+		switch name {
+		case "transfer":
+			c.transfer = w
+		case "read":
+			c.read = w
+		case "write":
+			c.write = w
+		default:
+			return fmt.Errorf("operation mix %q: want transfer, read or write", name)
+		}
+	}
+	if c.transfer+c.read+c.write == 0 {
+		return fmt.Errorf("operation mix %q has no weight", spec)
+	}
+	return nil
+}
+
+// memoKey is the register an account's readers and writers use
+func memoKey(account string) string {
+	return account + "/memo"
+}
+
+// generateWorkload draws the transactions of c and the balances the
+// accounts start with. A transfer is recorded as the producer and consumer
+// pair work has always recorded; a read or a write is a READER or WRITER of
+// the sender's memo register, a write storing its amount there.
+func generateWorkload(c workloadConfig) ([]TransactionData, map[string]int, error) {
+	if c.accounts < 2 {
+		return nil, nil, fmt.Errorf("%d accounts, transfers need two", c.accounts)
+	}
+	keyDist, err := parseDistribution(c.keys, 0, c.accounts-1)
+	if err != nil {
+		return nil, nil, err
+	}
+	if keyDist.lo < 0 || keyDist.hi >= c.accounts {
+		return nil, nil, fmt.Errorf("key distribution %q outside the %d accounts", c.keys, c.accounts)
+	}
+	amountDist, err := parseDistribution(c.amounts, 1, 50)
+	if err != nil {
+		return nil, nil, err
+	}
+	rng := rand.New(rand.NewSource(c.seed))
+	key, amount := keyDist.sampler(rng), amountDist.sampler(rng)
+
+	hexRunes := []rune("0123456789abcdef")
+	accounts := make([]string, c.accounts)
+	genesis := make(map[string]int, c.accounts)
+	for i := range accounts {
+		addr := make([]rune, 16)
+		for j := range addr {
+			addr[j] = hexRunes[rng.Intn(len(hexRunes))]
+		}
+		accounts[i] = string(addr)
+		genesis[accounts[i]] = rng.Intn(50)
+	}
+
+	total := c.transfer + c.read + c.write
+	issued := make(map[string]int64) // nonces handed out per sender
+	txns := make([]TransactionData, c.transactions)
+	for i := range txns {
+		sender := key()
+		t := &txns[i]
+		t.addrSender = accounts[sender]
+		t.tId = int32(i + 1)
+
+		switch op := rng.Intn(total); {
+		case op < c.transfer:
+			t.types = PRODUCER
+			receiver := key()
+			for receiver == sender {
+				receiver = rng.Intn(c.accounts)
+			}
+			t.addrReceiver = accounts[receiver]
+			t.amount = amount()
+			t.nonce = issued[t.addrSender]
+			issued[t.addrSender]++
+		case op < c.transfer+c.read:
+			t.types = READER
+		default:
+			t.types = WRITER
+			t.amount = amount()
+		}
+	}
+	return txns, genesis, nil
+}
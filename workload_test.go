@@ -0,0 +1,117 @@
+package main
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestParseDistribution(t *testing.T) {
+	for _, spec := range []string{"fixed", "uniform:5", "uniform:9:1", "zipf:1", "zipf:x", "hotspot:0:0.5", "hotspot:0.1:2", "normal"} {
+		if _, err := parseDistribution(spec, 0, 9); err == nil {
+			t.Errorf("%q accepted", spec)
+		}
+	}
+
+	d, err := parseDistribution("fixed:7", 0, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := d.sampler(rand.New(rand.NewSource(1)))(); v != 7 {
+		t.Errorf("fixed:7 drew %d", v)
+	}
+}
+
+// draws counts how often each value of [0, n) comes up
+func draws(t *testing.T, spec string, n int) []int {
+	d, err := parseDistribution(spec, 0, n-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := d.sampler(rand.New(rand.NewSource(1)))
+	counts := make([]int, n)
+	for i := 0; i < 10000; i++ {
+		v := next()
+		if v < 0 || v >= n {
+			t.Fatalf("%s drew %d outside [0, %d)", spec, v, n)
+		}
+		counts[v]++
+	}
+	return counts
+}
+
+func TestKeyDistributions(t *testing.T) {
+	uniform := draws(t, "uniform", 10)
+	for v, c := range uniform {
+		if c < 800 || c > 1200 {
+			t.Errorf("uniform drew %d %d times of 10000", v, c)
+		}
+	}
+
+	zipf := draws(t, "zipf:1.5", 100)
+	if zipf[0] < 3000 || zipf[0] < zipf[1] || zipf[1] < zipf[10] {
+		t.Errorf("zipf counts %v", zipf[:11])
+	}
+
+	// 90% of the draws on the first 10 of 100
+	hotspot := draws(t, "hotspot:0.1:0.9", 100)
+	hot := 0
+	for _, c := range hotspot[:10] {
+		hot += c
+	}
+	if hot < 8800 || hot > 9200 {
+		t.Errorf("hot keys drawn %d times of 10000", hot)
+	}
+}
+
+func TestGenerateWorkload(t *testing.T) {
+	c := workloadConfig{transactions: 1000, accounts: 20, keys: "zipf:1.2", amounts: "uniform:1:9", seed: 5}
+	if err := c.parseMix("transfer=50,read=30,write=20"); err != nil {
+		t.Fatal(err)
+	}
+	txns, genesis, err := generateWorkload(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txns) != 1000 || len(genesis) != 20 {
+		t.Fatalf("%d transactions over %d accounts", len(txns), len(genesis))
+	}
+
+	counts := make(map[Types]int)
+	next := make(map[string]int64)
+	for _, txn := range txns {
+		counts[txn.types]++
+		if _, ok := genesis[txn.addrSender]; !ok {
+			t.Fatalf("sender %s has no genesis balance", txn.addrSender)
+		}
+		if txn.types != PRODUCER {
+			continue
+		}
+		if txn.addrReceiver == txn.addrSender || txn.amount < 1 || txn.amount > 9 {
+			t.Errorf("transfer %+v", txn)
+		}
+		// nonces are handed out in order per sender
+		if txn.nonce != next[txn.addrSender] {
+			t.Errorf("nonce %d of %s, want %d", txn.nonce, txn.addrSender, next[txn.addrSender])
+		}
+		next[txn.addrSender]++
+	}
+	if counts[PRODUCER] < 450 || counts[PRODUCER] > 550 || counts[READER] < 250 || counts[READER] > 350 {
+		t.Errorf("operation mix %v", counts)
+	}
+
+	again, _, _ := generateWorkload(c)
+	if !reflect.DeepEqual(txns, again) {
+		t.Error("same seed, different workload")
+	}
+
+	for _, mix := range []string{"transfer=0", "transfer", "insert=5", "read=-1"} {
+		if err := c.parseMix(mix); err == nil {
+			t.Errorf("mix %q accepted", mix)
+		}
+	}
+	c.transfer, c.keys = 1, "uniform:0:20"
+	if _, _, err := generateWorkload(c); err == nil {
+		t.Error("keys beyond the accounts accepted")
+	}
+}